@@ -0,0 +1,248 @@
+// admin es la linea de comandos del panel de administracion: habla con la API
+// /admin/api de una instancia del servidor usando el admin_token.
+//
+//	admin [-url http://localhost:3000] [-token ...] <comando> [argumentos]
+//
+// Comandos: rooms, room <sala>, end <sala>, delete <sala>, kick <sala> <jugador>,
+// announce <mensaje...>, clear-announcement, diag.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// client llama a la API de admin de un servidor
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// apiError es el cuerpo de error de la API
+type apiError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// do hace el pedido y decodifica la respuesta en out (si no es nil)
+func (c *client) do(method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+"/admin/api"+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var e apiError
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error.Code != "" {
+			return fmt.Errorf("%s (%s)", e.Error.Message, e.Error.Code)
+		}
+		return fmt.Errorf("el servidor respondio %s", resp.Status)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// room es lo que se usa de cada sala al listar
+type room struct {
+	RoomID  string `json:"room_id"`
+	Status  string `json:"status"`
+	Private bool   `json:"private"`
+	Config  struct {
+		MaxPlayers int `json:"max_players"`
+	} `json:"config"`
+	Players []struct {
+		Name string `json:"name"`
+	} `json:"players"`
+	Connections int `json:"connections"`
+	AgeSeconds  int `json:"age_seconds"`
+}
+
+func main() {
+	flag.Usage = usage
+	baseURL := flag.String("url", envOr("ADMIN_URL", "http://localhost:3000"), "direccion del servidor (ADMIN_URL)")
+	token := flag.String("token", os.Getenv("ADMIN_TOKEN"), "token de admin (ADMIN_TOKEN)")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if *token == "" {
+		fmt.Fprintln(os.Stderr, "Falta el token: -token o ADMIN_TOKEN")
+		os.Exit(2)
+	}
+
+	c := &client{
+		baseURL: strings.TrimRight(*baseURL, "/"),
+		token:   *token,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if err := run(c, args[0], args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(c *client, cmd string, args []string) error {
+	switch cmd {
+	case "rooms":
+		var out struct {
+			Instance string `json:"instance"`
+			Rooms    []room `json:"rooms"`
+		}
+		if err := c.do(http.MethodGet, "/rooms", nil, &out); err != nil {
+			return err
+		}
+		printRooms(out.Instance, out.Rooms)
+		return nil
+	case "room":
+		if len(args) != 1 {
+			return fmt.Errorf("uso: room <sala>")
+		}
+		var out json.RawMessage
+		if err := c.do(http.MethodGet, "/rooms/"+url.PathEscape(args[0]), nil, &out); err != nil {
+			return err
+		}
+		var pretty bytes.Buffer
+		json.Indent(&pretty, out, "", "  ")
+		fmt.Println(pretty.String())
+		return nil
+	case "end":
+		if len(args) != 1 {
+			return fmt.Errorf("uso: end <sala>")
+		}
+		if err := c.do(http.MethodPost, "/rooms/"+url.PathEscape(args[0])+"/end", nil, nil); err != nil {
+			return err
+		}
+		fmt.Printf("La partida de %s volvio al lobby\n", args[0])
+		return nil
+	case "delete":
+		if len(args) != 1 {
+			return fmt.Errorf("uso: delete <sala>")
+		}
+		if err := c.do(http.MethodDelete, "/rooms/"+url.PathEscape(args[0]), nil, nil); err != nil {
+			return err
+		}
+		fmt.Printf("Sala %s cerrada\n", args[0])
+		return nil
+	case "kick":
+		if len(args) != 2 {
+			return fmt.Errorf("uso: kick <sala> <jugador>")
+		}
+		if err := c.do(http.MethodDelete, "/rooms/"+url.PathEscape(args[0])+"/players/"+url.PathEscape(args[1]), nil, nil); err != nil {
+			return err
+		}
+		fmt.Printf("Jugador %s expulsado de %s\n", args[1], args[0])
+		return nil
+	case "announce":
+		if len(args) == 0 {
+			return fmt.Errorf("uso: announce <mensaje>")
+		}
+		message := strings.Join(args, " ")
+		if err := c.do(http.MethodPut, "/announcement", map[string]string{"message": message}, nil); err != nil {
+			return err
+		}
+		fmt.Println("Anuncio publicado")
+		return nil
+	case "clear-announcement":
+		if err := c.do(http.MethodDelete, "/announcement", nil, nil); err != nil {
+			return err
+		}
+		fmt.Println("Anuncio borrado")
+		return nil
+	case "diag":
+		var out json.RawMessage
+		if err := c.do(http.MethodGet, "/diagnostics", nil, &out); err != nil {
+			return err
+		}
+		var pretty bytes.Buffer
+		json.Indent(&pretty, out, "", "  ")
+		fmt.Println(pretty.String())
+		return nil
+	}
+	usage()
+	return fmt.Errorf("comando desconocido: %q", cmd)
+}
+
+func printRooms(instance string, rooms []room) {
+	if instance != "" {
+		fmt.Printf("Instancia %s\n", instance)
+	}
+	if len(rooms) == 0 {
+		fmt.Println("No hay salas")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SALA\tESTADO\tJUGADORES\tCONEXIONES\tEDAD\tNOMBRES")
+	for _, r := range rooms {
+		names := make([]string, 0, len(r.Players))
+		for _, p := range r.Players {
+			names = append(names, p.Name)
+		}
+		id := r.RoomID
+		if r.Private {
+			id += " (privada)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%d\t%s\t%s\n", id, r.Status, len(r.Players), r.Config.MaxPlayers,
+			r.Connections, time.Duration(r.AgeSeconds)*time.Second, strings.Join(names, ", "))
+	}
+	w.Flush()
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Uso: admin [-url URL] [-token TOKEN] <comando> [argumentos]
+
+Comandos:
+  rooms                     lista las salas de la instancia
+  room <sala>               muestra el estado completo de una sala (con los dados)
+  end <sala>                termina la partida en curso y vuelve al lobby
+  delete <sala>             cierra la sala y desconecta a todos
+  kick <sala> <jugador>     expulsa a un jugador (por su ID)
+  announce <mensaje...>     muestra un anuncio en todas las salas
+  clear-announcement        borra el anuncio
+  diag                      diagnostico de la instancia: goroutines, timers, sesiones y version
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
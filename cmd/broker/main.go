@@ -0,0 +1,35 @@
+// broker es el servidor de pub/sub que comparten las instancias del juego cuando se
+// corre mas de una detras de un balanceador (ver -broker en el servidor).
+// Tambien sirve de stand-in local para probar el modo multi-instancia.
+package main
+
+import (
+	"dados-mentirosos/internal/broker"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+)
+
+func main() {
+	addr := flag.String("addr", ":7000", "direccion en la que escucha el broker")
+	secret := flag.String("secret", os.Getenv("BROKER_SECRET"), "secreto compartido con los servidores (o BROKER_SECRET)")
+	flag.Parse()
+
+	// sin secreto cualquiera que llegue al puerto podria quedarse con salas o mandar acciones
+	if len(*secret) < 16 {
+		fmt.Println("Error: el broker necesita -secret (o BROKER_SECRET) de al menos 16 caracteres")
+		os.Exit(1)
+	}
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Println("Error iniciando el broker:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Broker escuchando en %s\n", l.Addr())
+	if err := broker.Serve(l, broker.NewLocal(), *secret); err != nil {
+		fmt.Println("Error del broker:", err)
+		os.Exit(1)
+	}
+}
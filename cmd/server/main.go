@@ -1,11 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"dados-mentirosos/internal/broker"
+	"dados-mentirosos/internal/buildinfo"
+	"dados-mentirosos/internal/config"
 	"dados-mentirosos/internal/game"
 	"dados-mentirosos/internal/handlers"
+	"dados-mentirosos/internal/logging"
+	"dados-mentirosos/internal/metrics"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -13,41 +26,182 @@ import (
 )
 
 func main() {
+	// Se carga la configuracion (defaults < archivo < entorno < flags)
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Println("Configuracion invalida:", err)
+		os.Exit(2)
+	}
+	cfg.Print(os.Stdout)
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat))
+
 	// Se inicializan Dependencias
 	gm := game.NewGameManager()
+	gm.SetMaxRooms(cfg.MaxRooms)
+	gm.SetChatPolicy(cfg.ChatPolicy())
 	m := melody.New()
-	gameHandler := handlers.NewGameHandler(gm)
+
+	// Los templates se parsean una sola vez (embebidos salvo que se indique un directorio)
+	tmpls, err := handlers.NewTemplates(cfg.TemplateDir, cfg.Dev)
+	if err != nil {
+		slog.Error("error cargando templates", "err", err)
+		os.Exit(1)
+	}
+	gameHandler := handlers.NewGameHandler(gm, cfg, tmpls)
 	wsHandler := handlers.NewWSHandler(m, gm, gameHandler)
+	apiHandler := handlers.NewAPIHandler(gm, gameHandler)
+	adminHandler := handlers.NewAdminHandler(gm, gameHandler, wsHandler)
 
 	// Se configura el router
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(middleware.RequestID)
+	r.Use(gameHandler.RealIP) // antes del log y de los limites, que usan la IP del cliente
+	r.Use(handlers.RequestLogger)
 	r.Use(middleware.Recoverer)
+	r.Use(handlers.SecurityHeaders)
+	r.Use(gameHandler.CSRF)
 
 	// Servir archivos estáticos (CSS/JS) si los tuvieras locales
 	// r.Handle("/static/*", http.StripPrefix("/static/", http.FileServer(http.Dir("ui/static"))))
 
 	// Rutas HTTP
 	r.Get("/", gameHandler.Home)
-	r.Post("/create-room", gameHandler.CreateRoom)
-	r.Post("/join-room", gameHandler.JoinRoom)
+	r.With(gameHandler.LimitCreate).Post("/create-room", gameHandler.CreateRoom)
+	r.With(gameHandler.LimitJoin).Post("/join-room", gameHandler.JoinRoom)
+	r.Get("/rooms", gameHandler.PublicRooms)
+	r.With(gameHandler.LimitCreate).Post("/quick-match", gameHandler.QuickMatch)
+	r.Get("/quick-match", gameHandler.QuickMatchWait)
+	r.Get("/quick-match/status", gameHandler.QuickMatchStatus)
+	r.Post("/quick-match/cancel", gameHandler.QuickMatchCancel)
 	r.Get("/room/{roomID}", gameHandler.Room)
-	r.Post("/game/start", wsHandler.HandleStartGame)
-	r.Post("/game/bet", wsHandler.HandleBet)
-	r.Post("/game/liar", wsHandler.HandleLiar)
-	r.Post("/game/restart", wsHandler.HandleRestart)
-	r.Post("/game/config", wsHandler.HandleUpdateConfig)
-	r.Post("/game/next-round", wsHandler.HandleNextRound)
-
-	// Rutas WS
+	r.Post("/lang", gameHandler.SetLanguage) // selector de idioma
+
+	// API JSON para clientes que no son el navegador
+	r.Mount("/api/v1", apiHandler.Routes())
+
+	// Panel de administracion (solo con admin_token)
+	r.Mount("/admin", adminHandler.Routes())
+
+	// Rutas WS (las acciones del juego viajan como mensajes por el mismo socket)
 	r.Get("/ws/{roomID}", wsHandler.HandleRequest)
 
-	port := os.Getenv("PORT")
-	if port == ""{
-		port = "3000"
+	// Sondas del orquestador (el diagnostico completo esta en /admin/api/diagnostics)
+	r.Get("/healthz", gameHandler.Healthz)
+	r.Get("/readyz", gameHandler.Readyz)
+
+	// Metricas en formato Prometheus
+	handlers.RegisterMetrics(gm, m)
+	r.Handle("/metrics", metrics.Handler())
+
+	port := cfg.Port
+
+	// Las salas se reparten entre instancias a traves del broker (en memoria si hay una sola)
+	var b broker.Broker = broker.NewLocal()
+	var brokerLost <-chan struct{} // nil con el broker en memoria
+	if cfg.Broker != "" {
+		client, err := broker.Dial(cfg.Broker, cfg.BrokerSecret)
+		if err != nil {
+			slog.Error("error conectando al broker", "broker", cfg.Broker, "err", err)
+			os.Exit(1)
+		}
+		b = client
+		brokerLost = client.Done()
+	}
+	instance := cfg.InstanceID
+	if instance == "" {
+		instance = defaultInstanceID()
+	}
+	cluster, err := game.NewCluster(gm, b, instance)
+	if err != nil {
+		slog.Error("error iniciando el cluster", "err", err)
+		os.Exit(1)
 	}
+	// si otra instancia se queda con una sala propia, sus jugadores se reconectan a ella
+	gm.OnRoomLost(wsHandler.RoomLost)
+	// las salas vacias o sin actividad se cierran solas (y dejan de contar para max_rooms)
+	gm.OnRoomClosed(wsHandler.RoomClosed)
+	gm.StartReclaiming(cfg.RoomIdleTTL)
+	build := buildinfo.Get()
+	slog.Info("instancia iniciada", "instance", instance, "version", build.Version, "commit", build.Commit, "go_version", build.GoVersion)
+
+	// Si se configura un store las salas sobreviven a los reinicios
+	if cfg.StorePath != "" {
+		gm.SetStore(game.NewFileStore(cfg.StorePath))
+		restored, err := gm.Restore()
+		if err != nil {
+			slog.Error("error restaurando salas", "err", err)
+		} else if restored > 0 {
+			slog.Info("salas restauradas", "rooms", restored)
+		}
+	}
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	// Contexto que se cancela al recibir SIGINT o SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Iniciar Servidor
-	fmt.Println("Servidor corriendo en http://localhost:3000")
-	http.ListenAndServe(":"+port, r)
-}
\ No newline at end of file
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("servidor corriendo", "url", "http://localhost:"+port)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("error iniciando el servidor", "err", err)
+			os.Exit(1)
+		}
+		return
+	case <-ctx.Done():
+	case <-brokerLost:
+		// sin broker las salas de esta instancia quedan aisladas, mejor reiniciar
+		slog.Warn("se perdio la conexion con el broker")
+	}
+	stop() // un segundo Ctrl+C corta sin esperar
+
+	slog.Info("apagando servidor")
+	shutdown(srv, gm, gameHandler, wsHandler, cfg.ShutdownTimeout)
+	cluster.Close()
+	b.Close()
+}
+
+// defaultInstanceID arma un nombre de instancia unico: hostname + sufijo aleatorio
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "dados"
+	}
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
+
+// shutdown apaga el servidor en orden: sin salas nuevas, aviso a los jugadores,
+// drenado de HTTP, cierre de los WebSockets y recien ahi persistencia, asi se guardan
+// tambien las acciones que llegaron mientras se drenaba
+func shutdown(srv *http.Server, gm *game.GameManager, gameHandler *handlers.GameHandler, wsHandler *handlers.WSHandler, timeout time.Duration) {
+	gm.StartDraining()
+	gameHandler.Matchmaker.Stop()
+	wsHandler.BroadcastShutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("error drenando conexiones HTTP", "err", err)
+	}
+
+	if err := wsHandler.Close(); err != nil {
+		slog.Error("error cerrando WebSockets", "err", err)
+	}
+
+	if err := gm.Persist(); err != nil {
+		slog.Error("error guardando salas", "err", err)
+	}
+	slog.Info("servidor apagado")
+}
@@ -8,4 +8,4 @@ require (
 	github.com/olahol/melody v1.4.0
 )
 
-require github.com/gorilla/websocket v1.5.0 // indirect
+require github.com/gorilla/websocket v1.5.0
@@ -0,0 +1,37 @@
+// Package broker conecta varias instancias del servidor: reparte los eventos de las
+// salas (pub/sub) y registra que instancia es duena de cada sala.
+package broker
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrClosed = errors.New("broker cerrado")
+
+// Broker es el canal compartido entre instancias.
+// Los temas son strings libres ("room:abcde:state"); los datos viajan tal cual.
+type Broker interface {
+	// Publish manda data a todos los suscriptores del tema. No bloquea: un suscriptor
+	// que no lee a tiempo pierde mensajes.
+	Publish(topic string, data []byte) error
+
+	// Subscribe devuelve un canal con los mensajes del tema y una funcion para cancelar.
+	// El canal se cierra al cancelar o al cerrarse el broker.
+	Subscribe(topic string) (<-chan []byte, func(), error)
+
+	// Claim intenta quedarse con la clave por ttl (o renovarla si ya es suya).
+	// Devuelve el dueño actual: si es distinto de owner, la clave era de otro.
+	Claim(key, owner string, ttl time.Duration) (string, error)
+
+	// Release suelta la clave si owner es su dueño
+	Release(key, owner string) error
+
+	// Owner devuelve el dueño actual de la clave ("" si no tiene)
+	Owner(key string) (string, error)
+
+	Close() error
+}
+
+// subscriberBuffer es cuantos mensajes puede acumular un suscriptor lento
+const subscriberBuffer = 64
@@ -0,0 +1,130 @@
+package broker
+
+import (
+	"sync"
+	"time"
+)
+
+// Local es el broker en memoria: sirve para una sola instancia y es el que usa el
+// servidor TCP (ver tcp.go) para repartir los mensajes entre sus clientes.
+type Local struct {
+	mutex  sync.Mutex
+	subs   map[string]map[int]chan []byte
+	nextID int
+	claims map[string]claim
+	closed bool
+}
+
+type claim struct {
+	owner   string
+	expires time.Time
+}
+
+// NewLocal crea un broker en memoria
+func NewLocal() *Local {
+	return &Local{
+		subs:   make(map[string]map[int]chan []byte),
+		claims: make(map[string]claim),
+	}
+}
+
+func (b *Local) Publish(topic string, data []byte) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	for _, ch := range b.subs[topic] {
+		select {
+		case ch <- data:
+		default: // suscriptor lento, se descarta
+		}
+	}
+	return nil
+}
+
+func (b *Local) Subscribe(topic string) (<-chan []byte, func(), error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+
+	ch := make(chan []byte, subscriberBuffer)
+	b.nextID++
+	id := b.nextID
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[int]chan []byte)
+	}
+	b.subs[topic][id] = ch
+
+	cancel := func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		if c, ok := b.subs[topic][id]; ok {
+			delete(b.subs[topic], id)
+			if len(b.subs[topic]) == 0 {
+				delete(b.subs, topic)
+			}
+			close(c)
+		}
+	}
+	return ch, cancel, nil
+}
+
+func (b *Local) Claim(key, owner string, ttl time.Duration) (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return "", ErrClosed
+	}
+
+	now := time.Now()
+	if c, ok := b.claims[key]; ok && c.owner != owner && now.Before(c.expires) {
+		return c.owner, nil
+	}
+	b.claims[key] = claim{owner: owner, expires: now.Add(ttl)}
+	return owner, nil
+}
+
+func (b *Local) Release(key, owner string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if c, ok := b.claims[key]; ok && c.owner == owner {
+		delete(b.claims, key)
+	}
+	return nil
+}
+
+func (b *Local) Owner(key string) (string, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return "", ErrClosed
+	}
+
+	c, ok := b.claims[key]
+	if !ok || time.Now().After(c.expires) {
+		delete(b.claims, key)
+		return "", nil
+	}
+	return c.owner, nil
+}
+
+// Close cierra todas las suscripciones
+func (b *Local) Close() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for topic, subs := range b.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+		delete(b.subs, topic)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package broker
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// Protocolo de red: una linea JSON por mensaje sobre TCP.
+// Lo primero que manda el cliente es "hello" con el secreto compartido; si no coincide
+// el servidor corta la conexion. Despues manda operaciones ("pub", "sub", "unsub", "claim", "release", "owner")
+// con un id; el servidor contesta "reply" con el mismo id y empuja "msg" con los
+// mensajes de los temas suscritos. El servidor (Serve) guarda todo en un Local, asi
+// que sirve tanto de backbone entre instancias como de stand-in para probar localmente.
+type frame struct {
+	Op     string `json:"op"`
+	ID     uint64 `json:"id,omitempty"`
+	Topic  string `json:"topic,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Owner  string `json:"owner,omitempty"`
+	TTL    int64  `json:"ttl_ms,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+	Secret string `json:"secret,omitempty"` // solo en "hello"
+	Error  string `json:"error,omitempty"`
+}
+
+// ErrUnauthorized es la respuesta del servidor a un "hello" con otro secreto
+var ErrUnauthorized = errors.New("broker: secreto invalido")
+
+// requestTimeout es cuanto se espera la respuesta del servidor
+const requestTimeout = 5 * time.Second
+
+// Client es el Broker que habla con un servidor TCP (cmd/broker)
+type Client struct {
+	conn net.Conn
+
+	writeMutex sync.Mutex
+	enc        *json.Encoder
+
+	mutex   sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan frame
+	subs    map[string]map[int]chan []byte
+	nextSub int
+	closed  bool
+	done    chan struct{}
+}
+
+// Dial se conecta al servidor del broker y se presenta con el secreto compartido
+func Dial(addr, secret string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("conectando al broker %s: %w", addr, err)
+	}
+	c := &Client{
+		conn:    conn,
+		enc:     json.NewEncoder(conn),
+		pending: make(map[uint64]chan frame),
+		subs:    make(map[string]map[int]chan []byte),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+
+	if _, err := c.request(frame{Op: "hello", Secret: secret}); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("autenticando con el broker %s: %w", addr, err)
+	}
+	return c, nil
+}
+
+// Done se cierra cuando se pierde la conexion con el servidor
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// readLoop reparte las respuestas y los mensajes que llegan del servidor
+func (c *Client) readLoop() {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var f frame
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			continue
+		}
+
+		c.mutex.Lock()
+		switch f.Op {
+		case "reply":
+			if ch, ok := c.pending[f.ID]; ok {
+				delete(c.pending, f.ID)
+				ch <- f
+			}
+		case "msg":
+			for _, ch := range c.subs[f.Topic] {
+				select {
+				case ch <- f.Data:
+				default: // suscriptor lento, se descarta
+				}
+			}
+		}
+		c.mutex.Unlock()
+	}
+	c.shutdown()
+}
+
+// shutdown cierra las suscripciones y destraba los pedidos pendientes
+func (c *Client) shutdown() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for id, ch := range c.pending {
+		delete(c.pending, id)
+		ch <- frame{Op: "reply", ID: id, Error: ErrClosed.Error()}
+	}
+	for topic, subs := range c.subs {
+		for _, ch := range subs {
+			close(ch)
+		}
+		delete(c.subs, topic)
+	}
+	c.conn.Close()
+	close(c.done)
+}
+
+// request manda una operacion y espera la respuesta
+func (c *Client) request(f frame) (frame, error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return frame{}, ErrClosed
+	}
+	c.nextID++
+	f.ID = c.nextID
+	reply := make(chan frame, 1)
+	c.pending[f.ID] = reply
+	c.mutex.Unlock()
+
+	if err := c.send(f); err != nil {
+		c.mutex.Lock()
+		delete(c.pending, f.ID)
+		c.mutex.Unlock()
+		return frame{}, err
+	}
+
+	select {
+	case r := <-reply:
+		switch r.Error {
+		case "":
+			return r, nil
+		case ErrClosed.Error():
+			return r, ErrClosed
+		case ErrUnauthorized.Error():
+			return r, ErrUnauthorized
+		}
+		return r, errors.New(r.Error)
+	case <-time.After(requestTimeout):
+		c.mutex.Lock()
+		delete(c.pending, f.ID)
+		c.mutex.Unlock()
+		return frame{}, fmt.Errorf("broker: sin respuesta a %q", f.Op)
+	}
+}
+
+func (c *Client) send(f frame) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	return c.enc.Encode(f)
+}
+
+func (c *Client) Publish(topic string, data []byte) error {
+	_, err := c.request(frame{Op: "pub", Topic: topic, Data: data})
+	return err
+}
+
+// Subscribe se suscribe en el servidor la primera vez que se pide un tema;
+// los siguientes suscriptores del mismo tema se reparten del lado del cliente.
+func (c *Client) Subscribe(topic string) (<-chan []byte, func(), error) {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, nil, ErrClosed
+	}
+	first := len(c.subs[topic]) == 0
+	if first {
+		c.subs[topic] = make(map[int]chan []byte)
+	}
+	c.nextSub++
+	id := c.nextSub
+	ch := make(chan []byte, subscriberBuffer)
+	c.subs[topic][id] = ch
+	c.mutex.Unlock()
+
+	cancel := func() {
+		c.mutex.Lock()
+		sub, ok := c.subs[topic][id]
+		if !ok {
+			c.mutex.Unlock()
+			return
+		}
+		delete(c.subs[topic], id)
+		close(sub)
+		last := len(c.subs[topic]) == 0
+		if last {
+			delete(c.subs, topic)
+		}
+		c.mutex.Unlock()
+		if last {
+			c.request(frame{Op: "unsub", Topic: topic})
+		}
+	}
+
+	if first {
+		if _, err := c.request(frame{Op: "sub", Topic: topic}); err != nil {
+			cancel()
+			return nil, nil, err
+		}
+	}
+	return ch, cancel, nil
+}
+
+func (c *Client) Claim(key, owner string, ttl time.Duration) (string, error) {
+	r, err := c.request(frame{Op: "claim", Key: key, Owner: owner, TTL: ttl.Milliseconds()})
+	return r.Owner, err
+}
+
+func (c *Client) Release(key, owner string) error {
+	_, err := c.request(frame{Op: "release", Key: key, Owner: owner})
+	return err
+}
+
+func (c *Client) Owner(key string) (string, error) {
+	r, err := c.request(frame{Op: "owner", Key: key})
+	return r.Owner, err
+}
+
+// Close corta la conexion con el servidor
+func (c *Client) Close() error {
+	c.shutdown()
+	return nil
+}
+
+// Serve atiende clientes del broker en l usando b para guardar todo. Solo atiende a
+// los clientes que se presentan con secret. Devuelve el error del listener (por ejemplo
+// al cerrarlo).
+func Serve(l net.Listener, b *Local, secret string) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go serveConn(conn, b, secret)
+	}
+}
+
+// serveConn atiende a un cliente hasta que se desconecta
+func serveConn(conn net.Conn, b *Local, secret string) {
+	defer conn.Close()
+
+	var writeMutex sync.Mutex
+	enc := json.NewEncoder(conn)
+	send := func(f frame) {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+		enc.Encode(f)
+	}
+
+	// suscripciones de este cliente, se cancelan al desconectarse
+	subs := make(map[string]func())
+	defer func() {
+		for _, cancel := range subs {
+			cancel()
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	// el primer mensaje tiene que ser el "hello" con el secreto
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+	if !scanner.Scan() {
+		return
+	}
+	var hello frame
+	if err := json.Unmarshal(scanner.Bytes(), &hello); err != nil || hello.Op != "hello" ||
+		subtle.ConstantTimeCompare([]byte(hello.Secret), []byte(secret)) != 1 {
+		send(frame{Op: "reply", ID: hello.ID, Error: ErrUnauthorized.Error()})
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+	send(frame{Op: "reply", ID: hello.ID})
+
+	for scanner.Scan() {
+		var f frame
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			send(frame{Op: "reply", Error: "mensaje invalido"})
+			continue
+		}
+
+		reply := frame{Op: "reply", ID: f.ID}
+		var err error
+		switch f.Op {
+		case "pub":
+			err = b.Publish(f.Topic, f.Data)
+		case "sub":
+			if _, ok := subs[f.Topic]; ok {
+				break
+			}
+			var ch <-chan []byte
+			var cancel func()
+			ch, cancel, err = b.Subscribe(f.Topic)
+			if err != nil {
+				break
+			}
+			subs[f.Topic] = cancel
+			go func(topic string) {
+				for data := range ch {
+					send(frame{Op: "msg", Topic: topic, Data: data})
+				}
+			}(f.Topic)
+		case "unsub":
+			if cancel, ok := subs[f.Topic]; ok {
+				cancel()
+				delete(subs, f.Topic)
+			}
+		case "claim":
+			reply.Owner, err = b.Claim(f.Key, f.Owner, time.Duration(f.TTL)*time.Millisecond)
+		case "release":
+			err = b.Release(f.Key, f.Owner)
+		case "owner":
+			reply.Owner, err = b.Owner(f.Key)
+		default:
+			err = fmt.Errorf("operacion desconocida: %q", f.Op)
+		}
+		if err != nil {
+			reply.Error = err.Error()
+		}
+		send(reply)
+	}
+}
@@ -0,0 +1,218 @@
+package broker
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef"
+
+// connListener guarda las conexiones aceptadas para poder cortarlas desde el test
+type connListener struct {
+	net.Listener
+	mutex sync.Mutex
+	conns []net.Conn
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mutex.Lock()
+		l.conns = append(l.conns, conn)
+		l.mutex.Unlock()
+	}
+	return conn, err
+}
+
+// dropAll corta las conexiones abiertas, como si el broker se reiniciara
+func (l *connListener) dropAll() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	l.conns = nil
+}
+
+// startServer levanta el broker en 127.0.0.1:0 y lo apaga al terminar el test
+func startServer(t *testing.T) *connListener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := &connListener{Listener: l}
+	b := NewLocal()
+	go Serve(cl, b, testSecret)
+	t.Cleanup(func() {
+		l.Close()
+		cl.dropAll()
+		b.Close()
+	})
+	return cl
+}
+
+func dial(t *testing.T, l net.Listener) *Client {
+	t.Helper()
+	c, err := Dial(l.Addr().String(), testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func receive(t *testing.T, ch <-chan []byte) string {
+	t.Helper()
+	select {
+	case data, ok := <-ch:
+		if !ok {
+			t.Fatal("la suscripcion se cerro")
+		}
+		return string(data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no llego el mensaje")
+	}
+	return ""
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	l := startServer(t)
+	pub := dial(t, l)
+	sub := dial(t, l)
+
+	ch, cancel, err := sub.Subscribe("room:ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, cancelOther, err := sub.Subscribe("room:ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pub.Publish("room:ABCD", []byte("hola")); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, ch); got != "hola" {
+		t.Errorf("primer suscriptor recibio %q", got)
+	}
+	if got := receive(t, other); got != "hola" {
+		t.Errorf("segundo suscriptor recibio %q", got)
+	}
+
+	// otro tema no llega
+	if err := pub.Publish("room:ZZZZ", []byte("no")); err != nil {
+		t.Fatal(err)
+	}
+	cancelOther()
+	if _, ok := <-other; ok {
+		t.Error("el canal cancelado deberia estar cerrado")
+	}
+	if err := pub.Publish("room:ABCD", []byte("chau")); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, ch); got != "chau" {
+		t.Errorf("despues de cancelar el otro se recibio %q", got)
+	}
+	cancel()
+}
+
+func TestClaimTTL(t *testing.T) {
+	l := startServer(t)
+	a := dial(t, l)
+	b := dial(t, l)
+
+	owner, err := a.Claim("room:ABCD", "a", 300*time.Millisecond)
+	if err != nil || owner != "a" {
+		t.Fatalf("claim de a: owner=%q err=%v", owner, err)
+	}
+	// mientras no vence, b ve al duenio actual
+	owner, err = b.Claim("room:ABCD", "b", time.Second)
+	if err != nil || owner != "a" {
+		t.Fatalf("claim de b antes de vencer: owner=%q err=%v", owner, err)
+	}
+	if owner, _ := b.Owner("room:ABCD"); owner != "a" {
+		t.Errorf("Owner = %q, se esperaba a", owner)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	if owner, _ := b.Owner("room:ABCD"); owner != "" {
+		t.Errorf("Owner despues del TTL = %q, se esperaba vacio", owner)
+	}
+	owner, err = b.Claim("room:ABCD", "b", time.Second)
+	if err != nil || owner != "b" {
+		t.Fatalf("claim de b despues de vencer: owner=%q err=%v", owner, err)
+	}
+
+	// Release de otro duenio no hace nada
+	if err := a.Release("room:ABCD", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := a.Owner("room:ABCD"); owner != "b" {
+		t.Errorf("Owner despues del release ajeno = %q", owner)
+	}
+	if err := b.Release("room:ABCD", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := a.Owner("room:ABCD"); owner != "" {
+		t.Errorf("Owner despues del release = %q", owner)
+	}
+}
+
+func TestWrongSecret(t *testing.T) {
+	l := startServer(t)
+	_, err := Dial(l.Addr().String(), "otro-secreto-cualquiera")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Dial con otro secreto: err=%v, se esperaba ErrUnauthorized", err)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	l := startServer(t)
+	c := dial(t, l)
+	ch, _, err := c.Subscribe("room:ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Claim("room:ABCD", "a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// se corta la conexion del lado del servidor
+	l.dropAll()
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Done no se cerro al perder la conexion")
+	}
+	if _, ok := <-ch; ok {
+		t.Error("las suscripciones deberian cerrarse")
+	}
+	if err := c.Publish("room:ABCD", nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish sin conexion: err=%v", err)
+	}
+	if _, err := c.Claim("room:ABCD", "a", time.Minute); !errors.Is(err, ErrClosed) {
+		t.Errorf("Claim sin conexion: err=%v", err)
+	}
+
+	// el claim vive en el servidor, asi que al reconectar se puede renovar
+	again := dial(t, l)
+	owner, err := again.Claim("room:ABCD", "a", time.Minute)
+	if err != nil || owner != "a" {
+		t.Fatalf("renovar despues de reconectar: owner=%q err=%v", owner, err)
+	}
+	ch, cancel, err := again.Subscribe("room:ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if err := again.Publish("room:ABCD", []byte("de vuelta")); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, ch); got != "de vuelta" {
+		t.Errorf("despues de reconectar se recibio %q", got)
+	}
+}
@@ -0,0 +1,40 @@
+// Package buildinfo guarda la version del binario. Version y Commit se completan al
+// compilar:
+//
+//	go build -ldflags "-X dados-mentirosos/internal/buildinfo.Version=v1.2.0 -X dados-mentirosos/internal/buildinfo.Commit=$(git rev-parse --short HEAD)" ./cmd/server
+//
+// Sin ldflags el commit se toma de la informacion de VCS que agrega go build, si la hay.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// Info es la version del binario que esta corriendo
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get devuelve la informacion de compilacion
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+	if info.Commit == "" {
+		info.Commit = "desconocido"
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, s := range bi.Settings {
+				if s.Key == "vcs.revision" && s.Value != "" {
+					info.Commit = s.Value
+				}
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,498 @@
+package config
+
+import (
+	"bytes"
+	"dados-mentirosos/internal/game"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config reune todo lo configurable del servidor.
+// Precedencia (de menor a mayor): valores por defecto, archivo, variables de entorno, flags.
+type Config struct {
+	Port            string        `json:"port"`
+	TemplateDir     string        `json:"template_dir"` // vacio = templates embebidos en el binario
+	Dev             bool          `json:"dev"`          // relee los templates del disco cuando cambian
+	StorePath       string        `json:"store_path"`
+	LogLevel        string        `json:"log_level"`
+	LogFormat       string        `json:"log_format"` // "text" o "json"
+	RoomCodeLength  int           `json:"room_code_length"`
+	MaxRooms        int           `json:"max_rooms"` // 0 = sin limite
+	RoomIdleTTL     time.Duration `json:"room_idle_ttl"` // salas sin acciones por este tiempo se cierran
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+	SessionSecret   string        `json:"session_secret"` // vacio = aleatorio en cada arranque
+	SessionTTL      time.Duration `json:"session_ttl"`    // cuanto vale un token de sesion
+	AdminToken      string        `json:"admin_token"`    // vacio = sin panel de administracion
+	AllowedOrigins  []string      `json:"allowed_origins"` // origenes extra aceptados en el WebSocket ("*" = todos)
+	TrustedProxies  []string      `json:"trusted_proxies"` // redes (CIDR) de los proxies de los que se acepta X-Forwarded-For
+
+	// Varias instancias: broker compartido (host:puerto de cmd/broker, vacio = en memoria)
+	// y nombre de esta instancia (vacio = hostname + sufijo aleatorio)
+	Broker       string `json:"broker"`
+	BrokerSecret string `json:"broker_secret"` // secreto compartido con cmd/broker (obligatorio con broker)
+	InstanceID   string `json:"instance_id"`
+
+	// Limites de pedidos (0 = sin limite). Por IP se permite el cuadruple.
+	RateCreate  int `json:"rate_create"`  // salas creadas (o busquedas rapidas) por minuto
+	RateJoin    int `json:"rate_join"`    // ingresos a salas por minuto
+	RateActions int `json:"rate_actions"` // acciones de juego por segundo
+	WSRate      int `json:"ws_rate"`      // mensajes por segundo de cada conexion WebSocket
+
+	// Chat de las salas
+	ChatRate       int      `json:"chat_rate"`       // mensajes por minuto de cada jugador (0 = sin limite)
+	ChatScrollback int      `json:"chat_scrollback"` // mensajes que guarda cada sala y recibe quien entra
+	ChatFilter     []string `json:"chat_filter"`     // palabras que se tapan con asteriscos
+	ReactionRate   int      `json:"reaction_rate"`   // reacciones por minuto de cada jugador (0 = sin limite)
+
+	// Partida rapida
+	MatchMinPlayers int           `json:"match_min_players"` // con este grupo se arranca en el momento
+	MatchMaxWait    time.Duration `json:"match_max_wait"`    // pasado este tiempo se arranca con al menos 2
+
+	// Valores con los que se crea cada sala nueva
+	DefaultDicesAmount     int  `json:"default_dices_amount"`
+	DefaultMaxPlayers      int  `json:"default_max_players"`
+	DefaultTurnDuration    int  `json:"default_turn_duration"`
+	DefaultMinBetIncrement int  `json:"default_min_bet_increment"`
+	DefaultWildAces        bool `json:"default_wild_aces"`
+
+	ConfigFile string `json:"-"` // archivo del que se leyo la config (si hay)
+}
+
+// Default devuelve la configuracion con la que el servidor funcionaba antes de ser configurable
+func Default() Config {
+	return Config{
+		Port:            "3000",
+		LogLevel:        "info",
+		LogFormat:       "text",
+		RoomCodeLength:  5,
+		ShutdownTimeout: 10 * time.Second,
+		RoomIdleTTL:     30 * time.Minute,
+		SessionTTL:      24 * time.Hour,
+		RateCreate:      10,
+		RateJoin:        30,
+		RateActions:     5,
+		WSRate:          10,
+		ChatRate:        20,
+		ChatScrollback:  game.DefaultChatScrollback,
+		ReactionRate:    30,
+		MatchMinPlayers: 4,
+		MatchMaxWait:    30 * time.Second,
+
+		DefaultDicesAmount:     5,
+		DefaultMaxPlayers:      7,
+		DefaultTurnDuration:    0,
+		DefaultMinBetIncrement: 1,
+		DefaultWildAces:        false,
+	}
+}
+
+// TrustedProxyNets devuelve las redes de trusted_proxies ya parseadas (se validan en Validate)
+func (c Config) TrustedProxyNets() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range c.TrustedProxies {
+		if _, n, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+// ChatPolicy arma las reglas del chat de las salas
+func (c Config) ChatPolicy() game.ChatPolicy {
+	return game.ChatPolicy{Scrollback: c.ChatScrollback, Filter: c.ChatFilter}
+}
+
+// GameConfig arma la configuracion inicial de una sala nueva
+func (c Config) GameConfig() game.GameConfig {
+	return game.GameConfig{
+		DicesAmount:     c.DefaultDicesAmount,
+		MaxPlayers:      c.DefaultMaxPlayers,
+		TurnDuration:    c.DefaultTurnDuration,
+		MinBetIncrement: c.DefaultMinBetIncrement,
+		WildAces:        c.DefaultWildAces,
+	}
+}
+
+// Load arma la configuracion a partir de los argumentos de linea de comandos
+func Load(args []string) (Config, error) {
+	// Primera pasada solo para saber si hay un archivo de configuracion
+	scratch := Default()
+	if err := newFlagSet(&scratch).Parse(args); err != nil {
+		return Config{}, err
+	}
+	path := scratch.ConfigFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+
+	cfg := Default()
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return Config{}, err
+		}
+		cfg.ConfigFile = path
+	}
+
+	if err := loadEnv(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	// Segunda pasada: los flags tienen como default lo leido hasta ahora
+	// asi solo pisan los valores que se pasaron explicitamente
+	if err := newFlagSet(&cfg).Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	// En modo dev los templates se leen del repo si no se indico otro directorio
+	if cfg.Dev && cfg.TemplateDir == "" {
+		cfg.TemplateDir = "ui/html"
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// newFlagSet registra los flags apuntando a los campos de cfg
+func newFlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "archivo de configuracion JSON")
+	fs.StringVar(&cfg.Port, "port", cfg.Port, "puerto HTTP")
+	fs.StringVar(&cfg.TemplateDir, "templates", cfg.TemplateDir, "directorio de templates HTML (vacio = embebidos)")
+	fs.BoolVar(&cfg.Dev, "dev", cfg.Dev, "modo desarrollo: relee los templates de disco al cambiar")
+	fs.StringVar(&cfg.StorePath, "store", cfg.StorePath, "archivo donde persistir las salas (vacio = no persistir)")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "nivel de log: debug, info, warn, error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "formato de log: text o json")
+	fs.IntVar(&cfg.RoomCodeLength, "room-code-length", cfg.RoomCodeLength, "largo del codigo de sala")
+	fs.IntVar(&cfg.MaxRooms, "max-rooms", cfg.MaxRooms, "maximo de salas simultaneas (0 = sin limite)")
+	fs.DurationVar(&cfg.RoomIdleTTL, "room-idle-ttl", cfg.RoomIdleTTL, "se cierran las salas que pasan este tiempo sin acciones")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "tiempo maximo para drenar conexiones al apagar")
+	fs.StringVar(&cfg.SessionSecret, "session-secret", cfg.SessionSecret, "secreto para firmar las sesiones (vacio = aleatorio)")
+	fs.DurationVar(&cfg.SessionTTL, "session-ttl", cfg.SessionTTL, "cuanto vale un token de sesion desde que se emite")
+	fs.StringVar(&cfg.AdminToken, "admin-token", cfg.AdminToken, "token del panel /admin (vacio = deshabilitado)")
+	fs.Func("allowed-origins", "origenes extra aceptados en el WebSocket, separados por coma", func(v string) error {
+		cfg.AllowedOrigins = splitList(v)
+		return nil
+	})
+	fs.Func("trusted-proxies", "redes (CIDR) de los proxies de confianza para X-Forwarded-For, separadas por coma", func(v string) error {
+		cfg.TrustedProxies = splitList(v)
+		return nil
+	})
+	fs.StringVar(&cfg.Broker, "broker", cfg.Broker, "broker compartido entre instancias, host:puerto (vacio = en memoria)")
+	fs.StringVar(&cfg.BrokerSecret, "broker-secret", cfg.BrokerSecret, "secreto compartido con el broker")
+	fs.StringVar(&cfg.InstanceID, "instance-id", cfg.InstanceID, "nombre de esta instancia (vacio = hostname + sufijo aleatorio)")
+	fs.IntVar(&cfg.RateCreate, "rate-create", cfg.RateCreate, "salas creadas por minuto por sesion (0 = sin limite)")
+	fs.IntVar(&cfg.RateJoin, "rate-join", cfg.RateJoin, "ingresos a salas por minuto por sesion (0 = sin limite)")
+	fs.IntVar(&cfg.RateActions, "rate-actions", cfg.RateActions, "acciones de juego por segundo por sesion (0 = sin limite)")
+	fs.IntVar(&cfg.WSRate, "ws-rate", cfg.WSRate, "mensajes por segundo por conexion WebSocket (0 = sin limite)")
+	fs.IntVar(&cfg.ChatRate, "chat-rate", cfg.ChatRate, "mensajes de chat por minuto por jugador (0 = sin limite)")
+	fs.IntVar(&cfg.ChatScrollback, "chat-scrollback", cfg.ChatScrollback, "mensajes de chat que guarda cada sala")
+	fs.Func("chat-filter", "palabras tapadas en el chat, separadas por coma", func(v string) error {
+		cfg.ChatFilter = splitList(v)
+		return nil
+	})
+	fs.IntVar(&cfg.ReactionRate, "reaction-rate", cfg.ReactionRate, "reacciones por minuto por jugador (0 = sin limite)")
+	fs.IntVar(&cfg.MatchMinPlayers, "match-min-players", cfg.MatchMinPlayers, "jugadores para arrancar una partida rapida sin esperar")
+	fs.DurationVar(&cfg.MatchMaxWait, "match-max-wait", cfg.MatchMaxWait, "espera maxima de la partida rapida antes de arrancar con al menos 2")
+	fs.IntVar(&cfg.DefaultDicesAmount, "default-dices", cfg.DefaultDicesAmount, "dados por jugador en salas nuevas")
+	fs.IntVar(&cfg.DefaultMaxPlayers, "default-max-players", cfg.DefaultMaxPlayers, "maximo de jugadores en salas nuevas")
+	fs.IntVar(&cfg.DefaultTurnDuration, "default-turn-duration", cfg.DefaultTurnDuration, "segundos por turno en salas nuevas (0 = sin limite)")
+	fs.IntVar(&cfg.DefaultMinBetIncrement, "default-min-bet-increment", cfg.DefaultMinBetIncrement, "incremento minimo de apuesta en salas nuevas")
+	fs.BoolVar(&cfg.DefaultWildAces, "default-wild-aces", cfg.DefaultWildAces, "ases comodines en salas nuevas")
+	return fs
+}
+
+// loadFile lee un archivo JSON sobre la configuracion actual
+func loadFile(path string, cfg *Config) error {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" {
+		return fmt.Errorf("formato de configuracion no soportado %q (usar .json)", ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("leyendo configuracion: %w", err)
+	}
+
+	// las duraciones se escriben como texto ("10s") asi que se decodifican aparte
+	var file struct {
+		*Config
+		ShutdownTimeout string `json:"shutdown_timeout"`
+		MatchMaxWait    string `json:"match_max_wait"`
+		RoomIdleTTL     string `json:"room_idle_ttl"`
+		SessionTTL      string `json:"session_ttl"`
+	}
+	file.Config = cfg
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return fmt.Errorf("parseando %s: %w", path, err)
+	}
+	if file.ShutdownTimeout != "" {
+		d, err := time.ParseDuration(file.ShutdownTimeout)
+		if err != nil {
+			return fmt.Errorf("shutdown_timeout invalido: %w", err)
+		}
+		cfg.ShutdownTimeout = d
+	}
+	if file.MatchMaxWait != "" {
+		d, err := time.ParseDuration(file.MatchMaxWait)
+		if err != nil {
+			return fmt.Errorf("match_max_wait invalido: %w", err)
+		}
+		cfg.MatchMaxWait = d
+	}
+	if file.RoomIdleTTL != "" {
+		d, err := time.ParseDuration(file.RoomIdleTTL)
+		if err != nil {
+			return fmt.Errorf("room_idle_ttl invalido: %w", err)
+		}
+		cfg.RoomIdleTTL = d
+	}
+	if file.SessionTTL != "" {
+		d, err := time.ParseDuration(file.SessionTTL)
+		if err != nil {
+			return fmt.Errorf("session_ttl invalido: %w", err)
+		}
+		cfg.SessionTTL = d
+	}
+	return nil
+}
+
+// loadEnv aplica las variables de entorno definidas
+func loadEnv(cfg *Config) error {
+	setString(&cfg.Port, "PORT")
+	setString(&cfg.TemplateDir, "TEMPLATE_DIR")
+	setString(&cfg.StorePath, "STORE_PATH")
+	setString(&cfg.LogLevel, "LOG_LEVEL")
+	setString(&cfg.LogFormat, "LOG_FORMAT")
+	setString(&cfg.SessionSecret, "SESSION_SECRET")
+	setString(&cfg.AdminToken, "ADMIN_TOKEN")
+	setList(&cfg.AllowedOrigins, "ALLOWED_ORIGINS")
+	setList(&cfg.TrustedProxies, "TRUSTED_PROXIES")
+	setString(&cfg.Broker, "BROKER")
+	setString(&cfg.BrokerSecret, "BROKER_SECRET")
+	setString(&cfg.InstanceID, "INSTANCE_ID")
+	setList(&cfg.ChatFilter, "CHAT_FILTER")
+
+	errs := []error{
+		setBool(&cfg.Dev, "DEV"),
+		setInt(&cfg.RoomCodeLength, "ROOM_CODE_LENGTH"),
+		setInt(&cfg.MaxRooms, "MAX_ROOMS"),
+		setDuration(&cfg.ShutdownTimeout, "SHUTDOWN_TIMEOUT"),
+		setDuration(&cfg.RoomIdleTTL, "ROOM_IDLE_TTL"),
+		setDuration(&cfg.SessionTTL, "SESSION_TTL"),
+		setInt(&cfg.RateCreate, "RATE_CREATE"),
+		setInt(&cfg.RateJoin, "RATE_JOIN"),
+		setInt(&cfg.RateActions, "RATE_ACTIONS"),
+		setInt(&cfg.WSRate, "WS_RATE"),
+		setInt(&cfg.ChatRate, "CHAT_RATE"),
+		setInt(&cfg.ChatScrollback, "CHAT_SCROLLBACK"),
+		setInt(&cfg.ReactionRate, "REACTION_RATE"),
+		setInt(&cfg.MatchMinPlayers, "MATCH_MIN_PLAYERS"),
+		setDuration(&cfg.MatchMaxWait, "MATCH_MAX_WAIT"),
+		setInt(&cfg.DefaultDicesAmount, "DEFAULT_DICES"),
+		setInt(&cfg.DefaultMaxPlayers, "DEFAULT_MAX_PLAYERS"),
+		setInt(&cfg.DefaultTurnDuration, "DEFAULT_TURN_DURATION"),
+		setInt(&cfg.DefaultMinBetIncrement, "DEFAULT_MIN_BET_INCREMENT"),
+		setBool(&cfg.DefaultWildAces, "DEFAULT_WILD_ACES"),
+	}
+	return errors.Join(errs...)
+}
+
+func setString(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+func setList(dst *[]string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = splitList(v)
+	}
+}
+
+// splitList separa una lista por comas ignorando espacios y elementos vacios
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func setInt(dst *int, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q no es un numero", key, v)
+	}
+	*dst = i
+	return nil
+}
+
+func setBool(dst *bool, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q no es un booleano", key, v)
+	}
+	*dst = b
+	return nil
+}
+
+func setDuration(dst *time.Duration, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%s: %q no es una duracion", key, v)
+	}
+	*dst = d
+	return nil
+}
+
+// Validate revisa que los valores tengan sentido antes de arrancar
+func (c Config) Validate() error {
+	var errs []error
+
+	if p, err := strconv.Atoi(c.Port); err != nil || p < 1 || p > 65535 {
+		errs = append(errs, fmt.Errorf("port invalido: %q", c.Port))
+	}
+	if info, err := os.Stat(c.TemplateDir); c.TemplateDir != "" && (err != nil || !info.IsDir()) {
+		errs = append(errs, fmt.Errorf("template_dir no es un directorio: %q", c.TemplateDir))
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log_level invalido: %q", c.LogLevel))
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("log_format invalido: %q (text o json)", c.LogFormat))
+	}
+	if c.RoomCodeLength < 4 || c.RoomCodeLength > 12 {
+		errs = append(errs, fmt.Errorf("room_code_length debe estar entre 4 y 12"))
+	}
+	if c.MaxRooms < 0 {
+		errs = append(errs, fmt.Errorf("max_rooms no puede ser negativo"))
+	}
+	if c.RoomIdleTTL < time.Minute {
+		errs = append(errs, fmt.Errorf("room_idle_ttl debe ser de al menos 1m"))
+	}
+	if c.SessionSecret != "" && len(c.SessionSecret) < 16 {
+		errs = append(errs, fmt.Errorf("session_secret debe tener al menos 16 caracteres"))
+	}
+	if c.SessionTTL < time.Minute {
+		errs = append(errs, fmt.Errorf("session_ttl debe ser de al menos 1m"))
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < 16 {
+		errs = append(errs, fmt.Errorf("admin_token debe tener al menos 16 caracteres"))
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Errorf("allowed_origins: %q no es un origen (ej: https://ejemplo.com)", origin))
+		}
+	}
+	for _, cidr := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("trusted_proxies: %q no es una red CIDR (ej: 10.0.0.0/8)", cidr))
+		}
+	}
+	if c.Broker != "" {
+		if _, _, err := net.SplitHostPort(c.Broker); err != nil {
+			errs = append(errs, fmt.Errorf("broker debe ser host:puerto: %q", c.Broker))
+		}
+		if len(c.BrokerSecret) < 16 {
+			errs = append(errs, fmt.Errorf("broker_secret es obligatorio con broker y debe tener al menos 16 caracteres"))
+		}
+	}
+	if c.RateCreate < 0 || c.RateJoin < 0 || c.RateActions < 0 || c.WSRate < 0 || c.ChatRate < 0 || c.ReactionRate < 0 {
+		errs = append(errs, fmt.Errorf("los limites de pedidos no pueden ser negativos"))
+	}
+	if c.ChatScrollback < 1 || c.ChatScrollback > 500 {
+		errs = append(errs, fmt.Errorf("chat_scrollback debe estar entre 1 y 500"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown_timeout debe ser positivo"))
+	}
+	if c.MatchMinPlayers < 2 || c.MatchMinPlayers > c.DefaultMaxPlayers {
+		errs = append(errs, fmt.Errorf("match_min_players debe estar entre 2 y default_max_players"))
+	}
+	if c.MatchMaxWait <= 0 {
+		errs = append(errs, fmt.Errorf("match_max_wait debe ser positivo"))
+	}
+	if c.DefaultDicesAmount < 1 || c.DefaultDicesAmount > 6 {
+		errs = append(errs, fmt.Errorf("default_dices_amount debe estar entre 1 y 6"))
+	}
+	if c.DefaultMaxPlayers < 2 || c.DefaultMaxPlayers > 12 {
+		errs = append(errs, fmt.Errorf("default_max_players debe estar entre 2 y 12"))
+	}
+	if c.DefaultTurnDuration < 0 || c.DefaultTurnDuration > game.MaxTurnDuration {
+		errs = append(errs, fmt.Errorf("default_turn_duration debe estar entre 0 y %d", game.MaxTurnDuration))
+	}
+	if c.DefaultMinBetIncrement < 1 {
+		errs = append(errs, fmt.Errorf("default_min_bet_increment debe ser al menos 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// Print muestra la configuracion efectiva al arrancar
+func (c Config) Print(w io.Writer) {
+	source := "valores por defecto + entorno + flags"
+	if c.ConfigFile != "" {
+		source = c.ConfigFile + " + entorno + flags"
+	}
+	fmt.Fprintf(w, "Configuracion efectiva (%s):\n", source)
+	templates := "embebidos"
+	if c.TemplateDir != "" {
+		templates = c.TemplateDir
+	}
+	if c.Dev {
+		templates += " (dev, recarga al cambiar)"
+	}
+	fmt.Fprintf(w, "  port=%s templates=%s store=%q log=%s/%s\n", c.Port, templates, c.StorePath, c.LogLevel, c.LogFormat)
+	fmt.Fprintf(w, "  room_code_length=%d max_rooms=%d room_idle_ttl=%s shutdown_timeout=%s\n", c.RoomCodeLength, c.MaxRooms, c.RoomIdleTTL, c.ShutdownTimeout)
+	secret := "aleatorio"
+	if c.SessionSecret != "" {
+		secret = "configurado"
+	}
+	admin := "deshabilitado"
+	if c.AdminToken != "" {
+		admin = "habilitado"
+	}
+	fmt.Fprintf(w, "  session_secret=%s session_ttl=%s admin=%s allowed_origins=%v trusted_proxies=%v\n", secret, c.SessionTTL, admin, c.AllowedOrigins, c.TrustedProxies)
+	broker := "en memoria"
+	if c.Broker != "" {
+		broker = c.Broker + " (secreto configurado)"
+	}
+	fmt.Fprintf(w, "  broker=%s instance_id=%q\n", broker, c.InstanceID)
+	fmt.Fprintf(w, "  limites: crear=%d/min unirse=%d/min acciones=%d/s ws=%d/s\n", c.RateCreate, c.RateJoin, c.RateActions, c.WSRate)
+	fmt.Fprintf(w, "  chat: mensajes=%d/min historial=%d filtro=%d palabras reacciones=%d/min\n", c.ChatRate, c.ChatScrollback, len(c.ChatFilter), c.ReactionRate)
+	fmt.Fprintf(w, "  partida rapida: min_jugadores=%d espera_max=%s\n", c.MatchMinPlayers, c.MatchMaxWait)
+	fmt.Fprintf(w, "  sala por defecto: dados=%d jugadores=%d turno=%ds incremento=%d comodines=%t\n",
+		c.DefaultDicesAmount, c.DefaultMaxPlayers, c.DefaultTurnDuration, c.DefaultMinBetIncrement, c.DefaultWildAces)
+}
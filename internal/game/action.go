@@ -0,0 +1,95 @@
+package game
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrUnknownAction = newError("unknown_action", CategoryInvalid)
+
+// Action es un cambio pedido a la sala. Todos los metodos que modifican la sala arman
+// una Action, asi el mismo pedido se puede aplicar en el loop local o mandar a la
+// instancia duena de la sala (ver cluster.go).
+type Action struct {
+	Type     string     `json:"type"` // join, leave, ready, config, access, admit, start, bet, liar, next-round, reset, end, kick, chat, mute, clear-chat, react
+	PlayerID string     `json:"player_id,omitempty"`
+	Target   string     `json:"target,omitempty"` // jugador al que apunta la accion (mute)
+	Name     string     `json:"name,omitempty"`
+	Quantity int        `json:"quantity,omitempty"`
+	Face     int        `json:"face,omitempty"`
+	Ready    bool       `json:"ready,omitempty"`
+	Config   GameConfig `json:"config,omitempty"`
+	Private  bool       `json:"private,omitempty"`
+	Password string     `json:"password,omitempty"`
+	Text     string     `json:"text,omitempty"`
+	Muted    bool       `json:"muted,omitempty"`
+}
+
+// RelayFunc manda una accion a la instancia duena de la sala y devuelve su resultado
+type RelayFunc func(roomID string, a Action) error
+
+// exec aplica la accion en el loop de la sala (publicando el cambio) o, si la sala es
+// una copia de otra instancia, la reenvia a la duena
+func (r *Room) exec(a Action) error {
+	if r.relay != nil {
+		return r.relay(r.ID, a)
+	}
+
+	var err error
+	if a.Type == "admit" {
+		// admitir no cambia nada visible, no hace falta publicar
+		if doErr := r.do(func() { err = r.apply(a) }); doErr != nil {
+			return doErr
+		}
+	} else {
+		err = r.update(a.Type, func() error { return r.apply(a) })
+	}
+	if errors.Is(err, errNoChange) {
+		return nil
+	}
+	return err
+}
+
+// apply ejecuta la accion. Se llama desde el loop de la sala.
+func (r *Room) apply(a Action) error {
+	r.lastActivity = time.Now()
+	switch a.Type {
+	case "join":
+		return r.addPlayer(&Player{ID: a.PlayerID, Name: a.Name})
+	case "leave":
+		return r.removePlayer(a.PlayerID)
+	case "ready":
+		return r.setReady(a.PlayerID, a.Ready)
+	case "config":
+		return r.updateConfig(a.PlayerID, a.Config)
+	case "access":
+		r.setAccess(a.Private, a.Password)
+		return nil
+	case "admit":
+		return r.admit(a.PlayerID, a.Password)
+	case "start":
+		return r.startGame(a.PlayerID)
+	case "bet":
+		return r.placeBet(a.PlayerID, a.Quantity, a.Face)
+	case "liar":
+		_, err := r.callLiar(a.PlayerID)
+		return err
+	case "next-round":
+		return r.nextRound(a.PlayerID)
+	case "reset":
+		return r.restart(a.PlayerID)
+	case "end":
+		return r.forceEnd()
+	case "kick":
+		return r.kick(a.PlayerID)
+	case "chat":
+		return r.sendChat(a.PlayerID, a.Text)
+	case "mute":
+		return r.mute(a.PlayerID, a.Target, a.Muted)
+	case "clear-chat":
+		return r.clearChat(a.PlayerID)
+	case "react":
+		return r.react(a.PlayerID, a.Text)
+	}
+	return ErrUnknownAction
+}
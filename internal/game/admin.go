@@ -0,0 +1,70 @@
+package game
+
+import (
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrKicked         = newError("kicked", CategoryForbidden)
+	ErrPlayerNotFound = newError("player_not_found", CategoryNotFound)
+)
+
+// RoomInfo es lo que ve un administrador de una sala: todo el estado, dados incluidos
+type RoomInfo struct {
+	SpectatorView
+	CreatedAt   time.Time
+	HasPassword bool
+	Kicked      []string // jugadores que no pueden volver a entrar
+}
+
+// Info arma la vista de administracion de la sala
+func (r *Room) Info() RoomInfo {
+	var info RoomInfo
+	r.read(func() {
+		info = RoomInfo{
+			SpectatorView: r.spectatorView(),
+			CreatedAt:     r.CreatedAt,
+			HasPassword:   len(r.passwordHash) > 0,
+		}
+		// la vista publica oculta los dados durante la ronda, el admin los ve siempre
+		for i, seat := range info.Seats {
+			info.Seats[i].Dice = append([]Dice{}, r.Players[seat.ID].Dice...)
+		}
+		for id := range r.kicked {
+			info.Kicked = append(info.Kicked, id)
+		}
+	})
+	return info
+}
+
+// ForceEnd corta la partida en curso y vuelve la sala al lobby
+func (r *Room) ForceEnd() error {
+	return r.exec(Action{Type: "end"})
+}
+
+func (r *Room) forceEnd() error {
+	if r.Status == "WAITING" {
+		return errNoChange
+	}
+	r.reset()
+	slog.Info("partida terminada por un administrador", "room_id", r.ID)
+	return nil
+}
+
+// Kick saca a un jugador de la sala y no lo deja volver a entrar
+func (r *Room) Kick(playerID string) error {
+	return r.exec(Action{Type: "kick", PlayerID: playerID})
+}
+
+func (r *Room) kick(playerID string) error {
+	if _, ok := r.Players[playerID]; !ok {
+		return ErrPlayerNotFound
+	}
+	r.kicked[playerID] = true
+	delete(r.admitted, playerID)
+
+	// removePlayer tambien lo saca de la mesa si la partida esta en curso
+	slog.Info("jugador expulsado por un administrador", "room_id", r.ID, "player_id", playerID)
+	return r.removePlayer(playerID)
+}
@@ -0,0 +1,144 @@
+package game
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidChat = newError("invalid_chat", CategoryInvalid)
+	ErrMuted       = newError("muted", CategoryForbidden)
+	// lo devuelven los handlers cuando el jugador escribe mas rapido que chat_rate
+	ErrChatRateLimited = newError("chat_rate_limited", CategoryConflict)
+)
+
+// MaxChatLength es el largo maximo de un mensaje de chat (en caracteres)
+const MaxChatLength = 300
+
+// DefaultChatScrollback es cuantos mensajes guarda cada sala si no se configura otra cosa
+const DefaultChatScrollback = 50
+
+// ChatMessage es un mensaje del chat de la sala
+type ChatMessage struct {
+	PlayerID string    `json:"player_id"`
+	Name     string    `json:"name"`
+	Text     string    `json:"text"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// ChatPolicy son las reglas del chat que fija el servidor para todas las salas
+type ChatPolicy struct {
+	Scrollback int      // mensajes que guarda la sala (los recibe quien entra)
+	Filter     []string // palabras que se tapan con asteriscos (sin distinguir mayusculas)
+}
+
+// SendChat publica un mensaje del jugador en el chat de la sala
+func (r *Room) SendChat(playerID, text string) error {
+	return r.exec(Action{Type: "chat", PlayerID: playerID, Text: text})
+}
+
+func (r *Room) sendChat(playerID, text string) error {
+	p, ok := r.Players[playerID]
+	if !ok {
+		return ErrPlayerNotFound
+	}
+	if r.muted[playerID] {
+		return ErrMuted
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" || utf8.RuneCountInString(text) > MaxChatLength {
+		return ErrInvalidChat.With("max_length", MaxChatLength)
+	}
+
+	r.chat = append(r.chat, ChatMessage{
+		PlayerID: p.ID,
+		Name:     p.Name,
+		Text:     filterChat(text, r.chatPolicy.Filter),
+		SentAt:   time.Now(),
+	})
+	if extra := len(r.chat) - r.chatPolicy.Scrollback; extra > 0 {
+		r.chat = append([]ChatMessage(nil), r.chat[extra:]...)
+	}
+	return nil
+}
+
+// Mute silencia (o vuelve a dejar hablar) a un jugador. Solo el host.
+func (r *Room) Mute(hostID, playerID string, muted bool) error {
+	return r.exec(Action{Type: "mute", PlayerID: hostID, Target: playerID, Muted: muted})
+}
+
+func (r *Room) mute(hostID, playerID string, muted bool) error {
+	if host, ok := r.Players[hostID]; !ok || !host.IsHost {
+		return ErrNotHost
+	}
+	if _, ok := r.Players[playerID]; !ok {
+		return ErrPlayerNotFound
+	}
+	if r.muted[playerID] == muted {
+		return errNoChange
+	}
+	if muted {
+		r.muted[playerID] = true
+	} else {
+		delete(r.muted, playerID)
+	}
+	return nil
+}
+
+// ClearChat borra el historial del chat. Solo el host.
+func (r *Room) ClearChat(hostID string) error {
+	return r.exec(Action{Type: "clear-chat", PlayerID: hostID})
+}
+
+func (r *Room) clearChat(hostID string) error {
+	if host, ok := r.Players[hostID]; !ok || !host.IsHost {
+		return ErrNotHost
+	}
+	r.chat = nil
+	return nil
+}
+
+// Chat devuelve una copia del historial del chat, del mas viejo al mas nuevo
+func (r *Room) Chat() []ChatMessage {
+	var chat []ChatMessage
+	r.read(func() { chat = append([]ChatMessage{}, r.chat...) })
+	return chat
+}
+
+// filterChat tapa con asteriscos las palabras del filtro. Compara palabras enteras,
+// asi "clase" no se tapa por contener otra palabra.
+func filterChat(text string, filter []string) string {
+	if len(filter) == 0 {
+		return text
+	}
+
+	var out strings.Builder
+	var word []rune
+	flush := func() {
+		censored := false
+		for _, banned := range filter {
+			if strings.EqualFold(string(word), banned) {
+				censored = true
+				break
+			}
+		}
+		if censored {
+			out.WriteString(strings.Repeat("*", len(word)))
+		} else {
+			out.WriteString(string(word))
+		}
+		word = word[:0]
+	}
+	for _, c := range text {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			word = append(word, c)
+			continue
+		}
+		flush()
+		out.WriteRune(c)
+	}
+	flush()
+	return out.String()
+}
@@ -0,0 +1,564 @@
+package game
+
+import (
+	"dados-mentirosos/internal/broker"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRoomExists       = newError("room_exists", CategoryConflict)
+	ErrOwnerUnavailable = newError("owner_unavailable", CategoryUnavailable)
+)
+
+const (
+	ownershipTTL   = 15 * time.Second // si la duena no renueva en este tiempo, la sala queda libre
+	ownershipRenew = 5 * time.Second
+	relayTimeout   = 5 * time.Second
+)
+
+// Cluster reparte las salas entre varias instancias a traves de un Broker.
+//
+// Cada sala tiene una instancia duena (la que la creo) que corre su loop y sus timers.
+// Cualquier otra instancia puede aceptar el WebSocket de esa sala: arma una copia
+// (mirror) y reenvia las acciones de sus jugadores por "room:<id>:actions". La duena
+// contesta y le manda el estado de la sala por "instance:<id>:inbox", en el mismo orden
+// en que pasaron las cosas. El estado que viaja no lleva la contraseña y solo trae los
+// dados de los jugadores conectados a esa instancia (ver viewers).
+// Asi los handlers usan siempre un *Room, sea propio o copia.
+type Cluster struct {
+	broker   broker.Broker
+	instance string
+	manager  *GameManager
+
+	mutex   sync.Mutex
+	owned   map[string]func()                     // salas propias -> deja de atender sus acciones
+	mirrors map[string]*Room                      // copias de salas de otras instancias
+	watched map[string]map[string]bool            // copia -> jugadores cuyos dados ya manda la duena
+	syncing map[string][]chan stateMessage        // copias esperando su primer estado
+	relayed map[string]bool                       // salas propias cuyo estado ya se publica
+	viewers map[string]map[string]map[string]bool // sala propia -> instancia -> jugadores que ve
+	pending map[string]chan actionReply           // acciones reenviadas esperando respuesta
+	nextReq uint64
+	stop    chan struct{}
+}
+
+// actionRequest es una accion reenviada a la instancia duena
+type actionRequest struct {
+	ReqID  string `json:"req_id"`
+	Origin string `json:"origin"` // instancia que espera la respuesta
+	Action Action `json:"action"`
+}
+
+// actionReply es la respuesta de la duena ("" = sin error)
+type actionReply struct {
+	ReqID   string         `json:"req_id"`
+	Error   string         `json:"error,omitempty"` // codigo del error
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// stateMessage es el estado de una sala que la duena le manda a una instancia
+type stateMessage struct {
+	RoomID   string       `json:"room_id"`
+	Event    string       `json:"event"`
+	Snapshot RoomSnapshot `json:"snapshot"`
+	Reaction *Reaction    `json:"reaction,omitempty"` // solo en los eventos "react"
+}
+
+// inboxMessage es lo que recibe una instancia: la respuesta a una accion reenviada o
+// el estado de una sala que copia
+type inboxMessage struct {
+	Reply *actionReply  `json:"reply,omitempty"`
+	State *stateMessage `json:"state,omitempty"`
+}
+
+func roomKey(roomID string) string      { return "room:" + roomID }
+func actionsTopic(roomID string) string { return "room:" + roomID + ":actions" }
+func inboxTopic(instance string) string { return "instance:" + instance + ":inbox" }
+
+// NewCluster conecta el manager al broker con el nombre de instancia dado
+func NewCluster(gm *GameManager, b broker.Broker, instance string) (*Cluster, error) {
+	c := &Cluster{
+		broker:   b,
+		instance: instance,
+		manager:  gm,
+		owned:    make(map[string]func()),
+		mirrors:  make(map[string]*Room),
+		watched:  make(map[string]map[string]bool),
+		syncing:  make(map[string][]chan stateMessage),
+		relayed:  make(map[string]bool),
+		viewers:  make(map[string]map[string]map[string]bool),
+		pending:  make(map[string]chan actionReply),
+		stop:     make(chan struct{}),
+	}
+
+	inbox, _, err := b.Subscribe(inboxTopic(instance))
+	if err != nil {
+		return nil, fmt.Errorf("suscribiendo la bandeja de la instancia: %w", err)
+	}
+	go c.handleInbox(inbox)
+	go c.loop()
+
+	gm.mutex.Lock()
+	gm.cluster = c
+	gm.mutex.Unlock()
+	return c, nil
+}
+
+// Instance devuelve el nombre de esta instancia
+func (c *Cluster) Instance() string {
+	return c.instance
+}
+
+// Owner devuelve la instancia duena de la sala ("" si nadie la tiene)
+func (c *Cluster) Owner(roomID string) string {
+	owner, err := c.broker.Owner(roomKey(roomID))
+	if err != nil {
+		return ""
+	}
+	return owner
+}
+
+// claim reserva el codigo de sala para esta instancia
+func (c *Cluster) claim(roomID string) error {
+	owner, err := c.broker.Claim(roomKey(roomID), c.instance, ownershipTTL)
+	if err != nil {
+		return err
+	}
+	if owner != c.instance {
+		return ErrRoomExists
+	}
+	return nil
+}
+
+// attach reserva el codigo de la sala y empieza a atenderla. Sin cluster (una sola
+// instancia) no hace nada.
+func (c *Cluster) attach(room *Room) error {
+	if c == nil {
+		return nil
+	}
+	if err := c.claim(room.ID); err != nil {
+		return err
+	}
+	if err := c.own(room); err != nil {
+		c.release(room.ID)
+		return err
+	}
+	return nil
+}
+
+// own empieza a atender las acciones que otras instancias reenvian a la sala
+func (c *Cluster) own(room *Room) error {
+	actions, cancel, err := c.broker.Subscribe(actionsTopic(room.ID))
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	c.owned[room.ID] = cancel
+	c.mutex.Unlock()
+
+	go func() {
+		for data := range actions {
+			var req actionRequest
+			if err := json.Unmarshal(data, &req); err != nil {
+				continue
+			}
+			c.serve(room, req)
+		}
+	}()
+	return nil
+}
+
+// serve ejecuta una accion reenviada y le contesta a la instancia que la mando
+func (c *Cluster) serve(room *Room, req actionRequest) {
+	var err error
+	viewer := c.addViewer(room.ID, req.Origin, req.Action)
+	switch req.Action.Type {
+	case "sync":
+		// una instancia nueva abrio una copia: desde ahora se le manda el estado
+		c.startRelaying(room)
+	case "view":
+		// un jugador mira la sala desde otra instancia: alcanza con anotarlo
+	default:
+		err = room.exec(req.Action)
+	}
+	if viewer || req.Action.Type == "sync" {
+		// antes de la respuesta, asi la copia ya tiene el estado (y los dados de quien
+		// se conecto) cuando vuelve la accion
+		c.sendState(req.Origin, room.Snapshot(), stateMessage{RoomID: room.ID, Event: "sync"})
+	}
+
+	reply := actionReply{ReqID: req.ReqID}
+	if err != nil {
+		reply.Error = err.Error()
+		var gameErr *Error
+		if errors.As(err, &gameErr) {
+			reply.Details = gameErr.Details
+		}
+	}
+	c.send(req.Origin, inboxMessage{Reply: &reply})
+}
+
+// addViewer anota que la instancia origin copia la sala y que el jugador de la accion
+// esta conectado ahi (ve sus dados). Devuelve true si es nuevo para esa instancia.
+func (c *Cluster) addViewer(roomID, origin string, a Action) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	instances, ok := c.viewers[roomID]
+	if !ok {
+		instances = make(map[string]map[string]bool)
+		c.viewers[roomID] = instances
+	}
+	players, ok := instances[origin]
+	if !ok {
+		players = make(map[string]bool)
+		instances[origin] = players
+	}
+	added := !ok
+	// en kick el jugador es a quien se saca, no quien esta conectado
+	if a.PlayerID != "" && a.Type != "kick" && !players[a.PlayerID] {
+		players[a.PlayerID] = true
+		added = true
+	}
+	return added
+}
+
+// startRelaying manda el estado de la sala despues de cada cambio (una vez por sala)
+func (c *Cluster) startRelaying(room *Room) {
+	c.mutex.Lock()
+	if c.relayed[room.ID] {
+		c.mutex.Unlock()
+		return
+	}
+	c.relayed[room.ID] = true
+	c.mutex.Unlock()
+
+	updates, _ := room.Subscribe()
+	go func() {
+		for update := range updates {
+			snap := room.Snapshot()
+			c.mutex.Lock()
+			origins := make([]string, 0, len(c.viewers[room.ID]))
+			for origin := range c.viewers[room.ID] {
+				origins = append(origins, origin)
+			}
+			c.mutex.Unlock()
+			for _, origin := range origins {
+				c.sendState(origin, snap, stateMessage{RoomID: room.ID, Event: update.Event, Reaction: update.Reaction})
+			}
+		}
+	}()
+}
+
+// sendState le manda a una instancia el estado de la sala, sin lo que no puede ver
+func (c *Cluster) sendState(origin string, snap RoomSnapshot, msg stateMessage) {
+	c.mutex.Lock()
+	visible := make(map[string]bool, len(c.viewers[msg.RoomID][origin]))
+	for id := range c.viewers[msg.RoomID][origin] {
+		visible[id] = true
+	}
+	c.mutex.Unlock()
+
+	msg.Snapshot = snap.redacted(visible)
+	c.send(origin, inboxMessage{State: &msg})
+}
+
+func (c *Cluster) send(instance string, msg inboxMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		slog.Error("error serializando mensaje para otra instancia", "instance", instance, "err", err)
+		return
+	}
+	c.broker.Publish(inboxTopic(instance), data)
+}
+
+// mirror devuelve la copia local de una sala de otra instancia, creandola si hace falta
+func (c *Cluster) mirror(roomID string) (*Room, error) {
+	c.mutex.Lock()
+	if room, ok := c.mirrors[roomID]; ok {
+		c.mutex.Unlock()
+		return room, nil
+	}
+	c.mutex.Unlock()
+
+	owner := c.Owner(roomID)
+	if owner == "" || owner == c.instance {
+		return nil, ErrRoomNotFound
+	}
+
+	room := newRoom(roomID, GameConfig{})
+	room.relay = c.relay
+	room.viewer = func(playerID string) { c.watchPlayer(roomID, playerID) }
+	room.start()
+
+	// los estados que llegan mientras se arma la copia se guardan en states
+	states := make(chan stateMessage, 16)
+	c.mutex.Lock()
+	c.syncing[roomID] = append(c.syncing[roomID], states)
+	c.mutex.Unlock()
+
+	// se pide el estado actual: la duena lo manda antes que la respuesta
+	err := c.relay(roomID, Action{Type: "sync"})
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stopSyncing(roomID, states)
+	if err == nil && len(states) == 0 {
+		err = ErrOwnerUnavailable
+	}
+	if err != nil {
+		room.Close()
+		return nil, err
+	}
+	if existing, ok := c.mirrors[roomID]; ok {
+		// otra goroutine armo la copia mientras tanto
+		room.Close()
+		return existing, nil
+	}
+	// se aplica todo lo recibido antes de registrar la copia, con el mutex tomado asi
+	// handleInbox no aplica nada mas nuevo en el medio
+	for len(states) > 0 {
+		c.applyState(room, <-states)
+	}
+	c.mirrors[roomID] = room
+	return room, nil
+}
+
+// watchPlayer le pide a la duena, la primera vez, los dados de un jugador que mira la
+// copia desde esta instancia sin haber mandado ninguna accion (por ejemplo la API)
+func (c *Cluster) watchPlayer(roomID, playerID string) {
+	c.mutex.Lock()
+	known := c.watched[roomID][playerID]
+	c.mutex.Unlock()
+	if known {
+		return
+	}
+	if err := c.relay(roomID, Action{Type: "view", PlayerID: playerID}); err != nil {
+		return
+	}
+	c.mutex.Lock()
+	if c.watched[roomID] == nil {
+		c.watched[roomID] = make(map[string]bool)
+	}
+	c.watched[roomID][playerID] = true
+	c.mutex.Unlock()
+}
+
+// stopSyncing deja de guardar estados para una copia en armado. Se llama con el mutex tomado.
+func (c *Cluster) stopSyncing(roomID string, states chan stateMessage) {
+	waiting := c.syncing[roomID]
+	for i, ch := range waiting {
+		if ch == states {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(c.syncing, roomID)
+	} else {
+		c.syncing[roomID] = waiting
+	}
+}
+
+// applyState reemplaza el estado de la copia y lo publica a sus suscriptores locales
+func (c *Cluster) applyState(room *Room, msg stateMessage) {
+	room.do(func() {
+		room.load(msg.Snapshot)
+		room.reaction = msg.Reaction
+		room.publish(msg.Event)
+	})
+}
+
+// dropMirror descarta la copia de una sala (la duena desaparecio o se cerro el broker).
+// Si room no es nil solo se descarta si sigue siendo esa copia.
+func (c *Cluster) dropMirror(roomID string, room *Room) {
+	c.mutex.Lock()
+	m, ok := c.mirrors[roomID]
+	if ok && room != nil && m != room {
+		ok = false // ya es otra copia
+	}
+	if ok {
+		delete(c.mirrors, roomID)
+		delete(c.watched, roomID)
+	}
+	c.mutex.Unlock()
+	if ok {
+		m.Close()
+	}
+}
+
+// relay reenvia la accion a la duena de la sala y espera su respuesta
+func (c *Cluster) relay(roomID string, a Action) error {
+	c.mutex.Lock()
+	c.nextReq++
+	reqID := fmt.Sprintf("%s-%d", c.instance, c.nextReq)
+	reply := make(chan actionReply, 1)
+	c.pending[reqID] = reply
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.pending, reqID)
+		c.mutex.Unlock()
+	}()
+
+	data, _ := json.Marshal(actionRequest{ReqID: reqID, Origin: c.instance, Action: a})
+	if err := c.broker.Publish(actionsTopic(roomID), data); err != nil {
+		return ErrOwnerUnavailable
+	}
+
+	select {
+	case r := <-reply:
+		if r.Error == "" {
+			return nil
+		}
+		return remoteError(r.Error, r.Details)
+	case <-time.After(relayTimeout):
+		return ErrOwnerUnavailable
+	}
+}
+
+// handleInbox entrega las respuestas de las duenas a quien las espera y aplica el
+// estado de las salas copiadas, en el orden en que llegan
+func (c *Cluster) handleInbox(inbox <-chan []byte) {
+	for data := range inbox {
+		var msg inboxMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		if r := msg.Reply; r != nil {
+			c.mutex.Lock()
+			if ch, ok := c.pending[r.ReqID]; ok {
+				select {
+				case ch <- *r:
+				default: // respuesta repetida (dos duenas durante un traspaso)
+				}
+			}
+			c.mutex.Unlock()
+		}
+		if s := msg.State; s != nil {
+			c.mutex.Lock()
+			room := c.mirrors[s.RoomID]
+			for _, ch := range c.syncing[s.RoomID] {
+				select {
+				case ch <- *s:
+				default:
+				}
+			}
+			c.mutex.Unlock()
+			if room != nil {
+				c.applyState(room, *s)
+			}
+		}
+	}
+}
+
+// loop renueva la propiedad de las salas propias y descarta las copias huerfanas
+func (c *Cluster) loop() {
+	ticker := time.NewTicker(ownershipRenew)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.mutex.Lock()
+			owned := make([]string, 0, len(c.owned))
+			for id := range c.owned {
+				owned = append(owned, id)
+			}
+			mirrored := make([]string, 0, len(c.mirrors))
+			for id := range c.mirrors {
+				mirrored = append(mirrored, id)
+			}
+			c.mutex.Unlock()
+
+			for _, id := range owned {
+				if owner, err := c.broker.Claim(roomKey(id), c.instance, ownershipTTL); err == nil && owner != c.instance {
+					slog.Warn("la sala ahora es de otra instancia, se cierra la local", "room_id", id, "owner", owner)
+					c.lose(id)
+				}
+			}
+			for _, id := range mirrored {
+				if c.Owner(id) == "" {
+					c.dropMirror(id, nil)
+				}
+			}
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// lose deja de atender una sala que paso a otra instancia (no se renovo a tiempo).
+// La sala local se cierra para que las acciones no se acepten en dos lugares.
+func (c *Cluster) lose(roomID string) {
+	c.mutex.Lock()
+	cancel, ok := c.owned[roomID]
+	delete(c.owned, roomID)
+	delete(c.relayed, roomID)
+	delete(c.viewers, roomID)
+	c.mutex.Unlock()
+	if ok {
+		cancel()
+	}
+	c.manager.loseRoom(roomID)
+}
+
+// release suelta el codigo de una sala que no se llego a atender
+func (c *Cluster) release(roomID string) {
+	c.broker.Release(roomKey(roomID), c.instance)
+}
+
+// disown deja de atender la sala y suelta su codigo (la sala se elimino)
+func (c *Cluster) disown(roomID string) {
+	c.mutex.Lock()
+	cancel, ok := c.owned[roomID]
+	delete(c.owned, roomID)
+	delete(c.relayed, roomID)
+	delete(c.viewers, roomID)
+	c.mutex.Unlock()
+	if ok {
+		cancel()
+		c.broker.Release(roomKey(roomID), c.instance)
+	}
+}
+
+// Close suelta las salas propias y cierra las copias (al apagar la instancia)
+func (c *Cluster) Close() {
+	close(c.stop)
+
+	c.mutex.Lock()
+	owned := c.owned
+	c.owned = make(map[string]func())
+	mirrored := make([]string, 0, len(c.mirrors))
+	for id := range c.mirrors {
+		mirrored = append(mirrored, id)
+	}
+	c.mutex.Unlock()
+
+	for id, cancel := range owned {
+		cancel()
+		c.broker.Release(roomKey(id), c.instance)
+	}
+	for _, id := range mirrored {
+		c.dropMirror(id, nil)
+	}
+}
+
+// remoteError rearma el error del paquete cuando la accion se ejecuto en otra
+// instancia (los handlers los comparan con errors.Is y usan su categoria)
+func remoteError(code string, details map[string]any) error {
+	known, ok := LookupError(code)
+	if !ok {
+		return errors.New(code)
+	}
+	err := known
+	for k, v := range details {
+		err = err.With(k, v)
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package game
+
+import (
+	"dados-mentirosos/internal/broker"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newTestCluster arma dos instancias que comparten un broker en memoria
+func newTestCluster(t *testing.T) (*GameManager, *GameManager, *Cluster) {
+	t.Helper()
+	b := broker.NewLocal()
+	a := NewGameManager()
+	ca, err := NewCluster(a, b, "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := NewGameManager()
+	cb, err := NewCluster(other, b, "B")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cb.Close()
+		ca.Close()
+		b.Close()
+	})
+	return a, other, cb
+}
+
+func TestMirrorOnlySeesItsPlayersDice(t *testing.T) {
+	a, b, _ := newTestCluster(t)
+
+	room, err := a.CreateRoom("abcde", GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	room.SetAccess(true, "secreta")
+	if err := room.Admit("ana", "secreta"); err != nil {
+		t.Fatal(err)
+	}
+	if err := room.AddPlayer(&Player{ID: "ana", Name: "Ana"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Beto entra por la otra instancia
+	mirror, err := b.GetRoom("abcde")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mirror.Admit("beto", "secreta"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mirror.AddPlayer(&Player{ID: "beto", Name: "Beto"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := room.StartGame("ana"); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := room.Snapshot()
+	var anaDice, betoDice []Dice
+	for _, p := range owner.Players {
+		if p.ID == "ana" {
+			anaDice = p.Dice
+		} else {
+			betoDice = p.Dice
+		}
+	}
+
+	// la copia recibe el estado de forma asincrona
+	var snap RoomSnapshot
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		snap = mirror.Snapshot()
+		if snap.Status == "PLAYING" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if snap.Status != "PLAYING" {
+		t.Fatalf("la copia no recibio el inicio de la partida: %s", snap.Status)
+	}
+	if len(snap.PasswordHash) > 0 || len(snap.PasswordSalt) > 0 {
+		t.Error("la contraseña no deberia viajar a otra instancia")
+	}
+	for _, p := range snap.Players {
+		switch p.ID {
+		case "beto":
+			if !slices.Equal(p.Dice, betoDice) {
+				t.Errorf("dados de Beto en su instancia = %v, se esperaba %v", p.Dice, betoDice)
+			}
+		case "ana":
+			if len(p.Dice) != len(anaDice) {
+				t.Errorf("la copia deberia conservar la cantidad de dados de Ana")
+			}
+			for _, d := range p.Dice {
+				if d != 0 {
+					t.Fatalf("la copia ve los dados de Ana: %v", p.Dice)
+				}
+			}
+		}
+	}
+
+	// si Ana mira la sala desde la otra instancia, la duena le manda sus dados
+	view, err := mirror.PlayerView("ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(view.MyDice, anaDice) {
+		t.Errorf("Ana desde la copia ve %v, se esperaba %v", view.MyDice, anaDice)
+	}
+}
+
+func TestDuplicateReplyDoesNotBlock(t *testing.T) {
+	_, _, c := newTestCluster(t)
+
+	reply := make(chan actionReply, 1)
+	c.mutex.Lock()
+	c.pending["B-x"] = reply
+	c.mutex.Unlock()
+
+	// dos duenas contestan la misma accion durante un traspaso
+	data, _ := json.Marshal(inboxMessage{Reply: &actionReply{ReqID: "B-x"}})
+	inbox := make(chan []byte, 3)
+	inbox <- data
+	inbox <- data
+	inbox <- data
+	close(inbox)
+
+	done := make(chan struct{})
+	go func() {
+		c.handleInbox(inbox)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handleInbox se bloqueo con una respuesta repetida")
+	}
+	if r := <-reply; r.ReqID != "B-x" {
+		t.Errorf("respuesta = %+v", r)
+	}
+}
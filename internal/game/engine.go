@@ -0,0 +1,143 @@
+package game
+
+import "errors"
+
+var ErrRoomClosed = newError("room_closed", CategoryNotFound)
+
+// errNoChange lo devuelve un comando que no cambio nada (por ejemplo un timer viejo)
+var errNoChange = errors.New("no_change")
+
+// subscriberBuffer es cuantas actualizaciones puede acumular un suscriptor lento
+// antes de que se le reemplacen por una sola de tipo "sync"
+const subscriberBuffer = 16
+
+// RoomUpdate es lo que se publica a los suscriptores despues de cada cambio.
+// Event dice que paso: "join", "leave", "ready", "config", "access", "start", "bet", "liar",
+// "timeout", "next-round", "reset", "end", "kick", "chat", "mute", "clear-chat", "react" o "sync"
+// (se perdieron eventos, hay que redibujar todo).
+type RoomUpdate struct {
+	Event    string
+	View     SpectatorView
+	Chat     *ChatMessage // el mensaje nuevo en los eventos "chat"
+	Reaction *Reaction    // la reaccion en los eventos "react"
+}
+
+// command es una funcion que corre dentro del loop de la sala
+type command struct {
+	fn   func()
+	done chan struct{}
+}
+
+// Cada sala corre en su propia goroutine (run) que procesa los comandos de a uno:
+// acciones de los jugadores, timers y lecturas. Asi nada toca el estado de la sala
+// en paralelo y los cambios se publican en el mismo orden en que ocurrieron.
+
+// start lanza el loop de la sala
+func (r *Room) start() {
+	go r.run()
+}
+
+// run procesa los comandos hasta que la sala se cierra
+func (r *Room) run() {
+	for cmd := range r.cmds {
+		cmd.fn()
+		close(cmd.done)
+		if r.stopping {
+			close(r.closed)
+			return
+		}
+	}
+}
+
+// do ejecuta fn dentro del loop de la sala y espera a que termine
+func (r *Room) do(fn func()) error {
+	cmd := command{fn: fn, done: make(chan struct{})}
+	select {
+	case r.cmds <- cmd:
+		<-cmd.done
+		return nil
+	case <-r.closed:
+		return ErrRoomClosed
+	}
+}
+
+// read ejecuta una lectura dentro del loop (no publica nada)
+func (r *Room) read(fn func()) {
+	r.do(fn)
+}
+
+// update ejecuta un cambio dentro del loop y, si salio bien, lo publica
+func (r *Room) update(event string, fn func() error) error {
+	var err error
+	if doErr := r.do(func() {
+		err = fn()
+		if err == nil {
+			r.publish(event)
+		}
+	}); doErr != nil {
+		return doErr
+	}
+	return err
+}
+
+// publish manda la vista publica a los suscriptores sin bloquear el loop.
+// Si un suscriptor tiene el buffer lleno se descarta lo mas viejo y se le avisa con "sync".
+func (r *Room) publish(event string) {
+	update := RoomUpdate{Event: event, View: r.spectatorView()}
+	if event == "chat" && len(r.chat) > 0 {
+		// en las copias de otras instancias el mensaje llega con el snapshot
+		last := r.chat[len(r.chat)-1]
+		update.Chat = &last
+	}
+	if event == "react" {
+		update.Reaction = r.reaction
+	}
+	for _, ch := range r.subscribers {
+		select {
+		case ch <- update:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- RoomUpdate{Event: "sync", View: update.View}
+		}
+	}
+}
+
+// Subscribe devuelve un canal con las actualizaciones de la sala y una funcion para
+// dejar de recibirlas. El canal se cierra al cancelar o al cerrarse la sala.
+func (r *Room) Subscribe() (<-chan RoomUpdate, func()) {
+	ch := make(chan RoomUpdate, subscriberBuffer)
+	var id int
+	if err := r.do(func() {
+		r.nextSubID++
+		id = r.nextSubID
+		r.subscribers[id] = ch
+	}); err != nil {
+		close(ch)
+		return ch, func() {}
+	}
+
+	cancel := func() {
+		r.do(func() {
+			if c, ok := r.subscribers[id]; ok {
+				delete(r.subscribers, id)
+				close(c)
+			}
+		})
+	}
+	return ch, cancel
+}
+
+// Close detiene el loop y los timers de la sala y cierra las suscripciones
+func (r *Room) Close() {
+	r.do(func() {
+		r.stopTurnTimer()
+		for id, ch := range r.subscribers {
+			delete(r.subscribers, id)
+			close(ch)
+		}
+		r.stopping = true
+	})
+}
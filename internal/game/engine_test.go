@@ -0,0 +1,195 @@
+package game
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Apuestas, desafios y timers de turno compitiendo por el loop de la sala mientras
+// otros leen y se suscriben. Correr con -race.
+func TestConcurrentBetsAndTimers(t *testing.T) {
+	room := newTestGameWith(t, GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1, TurnDuration: 1})
+	updates, cancel := room.Subscribe()
+	stop := time.Now().Add(3500 * time.Millisecond)
+
+	// errores esperables cuando la vista que se leyo ya quedo vieja
+	stale := []error{ErrNotYourTurn, ErrNotPlaying, ErrNotFinished, ErrInvalidBet, ErrNoBetMade}
+	check := func(err error) {
+		for _, e := range stale {
+			if errors.Is(err, e) {
+				return
+			}
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	var wg sync.WaitGroup
+
+	// Ana apuesta justo cuando vence su turno (segun la apuesta, antes o despues del timer);
+	// Beto apuesta enseguida y desafia desde 3 dados. Ana es host y arranca cada ronda.
+	play := func(id string) {
+		defer wg.Done()
+		var handled time.Time
+		for time.Now().Before(stop) {
+			view, err := room.PlayerView(id)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			switch {
+			case view.Status == "FINISHED" && view.IsHost:
+				check(room.NextRound(id))
+			case view.Status != "PLAYING" || !view.IsMyTurn || view.TurnDeadline.Equal(handled):
+				time.Sleep(time.Millisecond)
+			case id == "beto" && view.CurrentBetQuantity >= 3:
+				_, err := room.CallLiar(id)
+				check(err)
+			case id == "beto":
+				check(room.PlaceBet(id, view.CurrentBetQuantity+1, 3))
+			default:
+				handled = view.TurnDeadline
+				wait := time.Until(view.TurnDeadline) - 10*time.Millisecond
+				if view.CurrentBetQuantity%2 == 0 {
+					wait += 150 * time.Millisecond // el timer gana seguro
+				}
+				time.Sleep(wait)
+				check(room.PlaceBet(id, view.CurrentBetQuantity+1, 3))
+			}
+		}
+	}
+	wg.Add(2)
+	go play("ana")
+	go play("beto")
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(stop) {
+				room.SpectatorView()
+				room.Snapshot()
+				if _, err := room.PlayerView("beto"); err != nil {
+					t.Error(err)
+					return
+				}
+				_, unsubscribe := room.Subscribe()
+				unsubscribe()
+			}
+		}()
+	}
+
+	// dentro de una ronda la apuesta nunca baja y cada timeout la sube
+	events := make(map[string]int)
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		last := 0
+		for u := range updates {
+			events[u.Event]++
+			switch u.Event {
+			case "bet", "timeout":
+				if u.View.CurrentBetQuantity <= last {
+					t.Errorf("%s: la apuesta no subio de %d (quedo en %d)", u.Event, last, u.View.CurrentBetQuantity)
+				}
+			}
+			last = u.View.CurrentBetQuantity
+		}
+	}()
+
+	wg.Wait()
+	cancel()
+	<-collected
+	for _, event := range []string{"bet", "timeout", "liar", "next-round"} {
+		if events[event] == 0 {
+			t.Errorf("no hubo ningun evento %q: %v", event, events)
+		}
+	}
+}
+
+func TestSlowSubscriberGetsSync(t *testing.T) {
+	room := newTestGame(t)
+	updates, cancel := room.Subscribe()
+	defer cancel()
+
+	room.do(func() {
+		for i := 0; i < subscriberBuffer+5; i++ {
+			room.publish("chat")
+		}
+	})
+
+	var got []string
+	for len(updates) > 0 {
+		got = append(got, (<-updates).Event)
+	}
+	if len(got) != subscriberBuffer {
+		t.Fatalf("se acumularon %d actualizaciones, el buffer es de %d", len(got), subscriberBuffer)
+	}
+	if got[0] != "chat" || got[len(got)-1] != "sync" {
+		t.Errorf("eventos = %v, se esperaba que termine en sync", got)
+	}
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	room := newTestGameWith(t, GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1, TurnDuration: 60})
+	updates, _ := room.Subscribe()
+	room.Close()
+
+	done := make(chan struct{})
+	go func() {
+		for range updates {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close no cerro la suscripcion")
+	}
+
+	current := room.SpectatorView().CurrentPlayerID
+	if err := room.PlaceBet(current, 1, 2); !errors.Is(err, ErrRoomClosed) {
+		t.Errorf("PlaceBet en una sala cerrada = %v, se esperaba ErrRoomClosed", err)
+	}
+	late, _ := room.Subscribe()
+	if _, ok := <-late; ok {
+		t.Error("suscribirse a una sala cerrada deberia devolver un canal cerrado")
+	}
+}
+
+func TestRoundControlsRequireHost(t *testing.T) {
+	room := newTestGame(t)
+	if err := room.PlaceBet(room.SpectatorView().CurrentPlayerID, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := room.CallLiar(room.SpectatorView().CurrentPlayerID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"beto", "carla"} {
+		if err := room.NextRound(id); !errors.Is(err, ErrNotHost) {
+			t.Errorf("NextRound(%s) = %v, se esperaba ErrNotHost", id, err)
+		}
+		if err := room.Reset(id); !errors.Is(err, ErrNotHost) {
+			t.Errorf("Reset(%s) = %v, se esperaba ErrNotHost", id, err)
+		}
+	}
+	if status := room.SpectatorView().Status; status != "FINISHED" {
+		t.Fatalf("un pedido rechazado cambio la sala a %s", status)
+	}
+
+	if err := room.NextRound("ana"); err != nil {
+		t.Fatal(err)
+	}
+	if err := room.NextRound("ana"); !errors.Is(err, ErrNotFinished) {
+		t.Errorf("NextRound con la ronda en curso = %v, se esperaba ErrNotFinished", err)
+	}
+	if err := room.Reset("ana"); err != nil {
+		t.Fatal(err)
+	}
+	if status := room.SpectatorView().Status; status != "WAITING" {
+		t.Errorf("despues del reset la sala esta %s", status)
+	}
+}
@@ -0,0 +1,71 @@
+package game
+
+import "slices"
+
+// Category agrupa los errores del juego segun que tiene que hacer quien los recibe.
+// Los handlers la traducen a un status HTTP.
+type Category string
+
+const (
+	CategoryInvalid     Category = "invalid"     // el pedido esta mal armado o fuera de rango
+	CategoryForbidden   Category = "forbidden"   // el jugador no tiene permiso (no es el host, contraseña, expulsado)
+	CategoryConflict    Category = "conflict"    // no se puede en el estado actual de la sala
+	CategoryNotFound    Category = "not-found"   // la sala o el jugador no existen
+	CategoryUnavailable Category = "unavailable" // el servidor no puede atender ahora (reiniciando, sin lugar, otra instancia caida)
+)
+
+// Error es un error del juego: un codigo estable (el "code" de la API y la clave
+// error.<codigo> de los catalogos de i18n), su categoria y datos opcionales que
+// ayudan al cliente, como la apuesta minima.
+type Error struct {
+	Code     string         `json:"code"`
+	Category Category       `json:"category"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code
+}
+
+// Is compara por codigo, asi un error con detalles sigue cumpliendo
+// errors.Is(err, ErrInvalidBet)
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// With devuelve una copia del error con un detalle agregado
+func (e *Error) With(key string, value any) *Error {
+	details := make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+	details[key] = value
+	return &Error{Code: e.Code, Category: e.Category, Details: details}
+}
+
+// errorsByCode son todos los errores del paquete, para reconstruirlos a partir del
+// codigo (respuestas de otra instancia, ?error= de la pantalla de inicio)
+var errorsByCode = make(map[string]*Error)
+
+func newError(code string, category Category) *Error {
+	e := &Error{Code: code, Category: category}
+	errorsByCode[code] = e
+	return e
+}
+
+// ErrorCodes devuelve los codigos de todos los errores del juego, ordenados
+func ErrorCodes() []string {
+	codes := make([]string, 0, len(errorsByCode))
+	for code := range errorsByCode {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// LookupError devuelve el error del juego con ese codigo
+func LookupError(code string) (*Error, bool) {
+	e, ok := errorsByCode[code]
+	return e, ok
+}
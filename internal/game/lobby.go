@@ -1,20 +1,76 @@
 package game
 
 import (
-	"errors"
+	"log/slog"
 	"math/rand"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Los errores del juego son *Error (ver errors.go): un codigo estable y una categoria
+// que los handlers traducen al idioma de cada jugador y a un status HTTP.
 var (
-	ErrRoomFull = errors.New("la sala esta llena")
-	ErrGameStarted = errors.New("el juego ya ha comenzado")
-	ErrPlayerExist = errors.New("el jugador ya esta en la sala")
+	ErrRoomFull = newError("room_full", CategoryConflict)
+	ErrGameStarted = newError("game_started", CategoryConflict)
+	ErrPlayerExist = newError("player_exists", CategoryConflict)
+	ErrWrongPassword = newError("wrong_password", CategoryForbidden)
+	ErrNotAdmitted = newError("not_admitted", CategoryForbidden)
+	ErrNotHost = newError("not_host", CategoryForbidden)
+	ErrNotEnoughPlayers = newError("not_enough_players", CategoryConflict)
+	ErrInvalidName = newError("invalid_name", CategoryInvalid)
+	ErrNameTaken = newError("name_taken", CategoryConflict)
 )
 
+// MaxNameLength es el largo maximo del nombre de un jugador (en caracteres)
+const MaxNameLength = 20
 
-// NewRoom crea una instancia de una sala vacia
+// MaxTurnDuration es el maximo de segundos por turno que acepta una sala
+const MaxTurnDuration = 600
+
+// NormalizePlayerName limpia el nombre (espacios de mas) y valida largo y caracteres.
+// Todo nombre que entra a una sala tiene que pasar por aca.
+func NormalizePlayerName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrInvalidName.With("max_length", MaxNameLength)
+	}
+	for _, c := range name {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(" .-_", c) {
+			return "", ErrInvalidName.With("max_length", MaxNameLength)
+		}
+	}
+	return name, nil
+}
+
+// NameTaken indica si otro jugador de la sala ya usa ese nombre (sin distinguir mayusculas)
+func (r *Room) NameTaken(name string) bool {
+	taken := false
+	r.read(func() { taken = r.nameTaken(name, "") })
+	return taken
+}
+
+// nameTaken se llama desde el loop de la sala. exceptID permite ignorar al propio jugador.
+func (r *Room) nameTaken(name, exceptID string) bool {
+	for id, p := range r.Players {
+		if id != exceptID && strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+
+// NewRoom crea una instancia de una sala vacia y arranca su loop
 func NewRoom(id string, config GameConfig) *Room {
+	room := newRoom(id, config)
+	room.start()
+	return room
+}
+
+// newRoom crea la sala sin arrancar el loop (para poder restaurarla antes)
+func newRoom(id string, config GameConfig) *Room {
 	source := rand.NewSource(time.Now().UnixNano())
 	generator := rand.New(source)
 
@@ -24,13 +80,39 @@ func NewRoom(id string, config GameConfig) *Room {
 		Players: make(map[string]*Player),
 		Status: "WAITING",
 		rng: generator,
+		admitted: make(map[string]bool),
+		kicked: make(map[string]bool),
+		CreatedAt: time.Now(),
+		lastActivity: time.Now(),
+		muted: make(map[string]bool),
+		chatPolicy: ChatPolicy{Scrollback: DefaultChatScrollback},
+		cmds: make(chan command),
+		closed: make(chan struct{}),
+		subscribers: make(map[int]chan RoomUpdate),
 	}
 }
 
 // AddPlayer maneja que un jugador se una a la sala
 func (r *Room) AddPlayer(p *Player) error {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock() // se ejecuta al salir de la funcion
+	return r.exec(Action{Type: "join", PlayerID: p.ID, Name: p.Name})
+}
+
+func (r *Room) addPlayer(p *Player) error {
+	// Se chequea que el jugador no este en la sala (por ejemplo al reconectarse)
+	if _, exists := r.Players[p.ID]; exists {
+		return ErrPlayerExist
+	}
+
+	// Los permisos van antes que el estado de la sala: quien no puede entrar no tiene
+	// que enterarse de si la partida arranco o si esta llena
+	if r.kicked[p.ID] {
+		return ErrKicked
+	}
+
+	// En salas privadas solo entran los que pasaron por Admit
+	if r.Private && !r.admitted[p.ID] {
+		return ErrNotAdmitted
+	}
 
 	// Se chequea que la partida no haya comenzado
 	if r.Status != "WAITING" {
@@ -39,13 +121,18 @@ func (r *Room) AddPlayer(p *Player) error {
 
 	// Se chequea que la sala no este llena
 	if len(r.Players) >= r.Config.MaxPlayers {
-		return ErrRoomFull
+		return ErrRoomFull.With("max_players", r.Config.MaxPlayers)
 	}
 
-	// Se chequea que el jugador no este en la sala (quizas innecesario)
-	if _, exists := r.Players[p.ID]; exists {
-		return ErrPlayerExist
+	// El nombre se valida aca tambien, por si el llamador no lo hizo
+	name, err := NormalizePlayerName(p.Name)
+	if err != nil {
+		return err
 	}
+	if r.nameTaken(name, p.ID) {
+		return ErrNameTaken
+	}
+	p.Name = name
 
 	// El primero en unirse sera el admin y si la sala esta vacia es el primero
 	if len(r.Players) == 0 {
@@ -63,12 +150,17 @@ func (r *Room) AddPlayer(p *Player) error {
 
 // RemovePlayer maneja el eliminar a un jugador y reasigna el host si es necesario.
 func (r *Room) RemovePlayer(playerID string) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+	r.exec(Action{Type: "leave", PlayerID: playerID})
+}
 
+func (r *Room) removePlayer(playerID string) error {
 	player, exists := r.Players[playerID]
 	if !exists {
-		return
+		return errNoChange
+	}
+
+	if r.Status != "WAITING" {
+		r.leaveTable(playerID)
 	}
 
 	wasHost := player.IsHost
@@ -81,20 +173,48 @@ func (r *Room) RemovePlayer(playerID string) {
 			break // solo un host
 		}
 	}
+	if len(r.Players) == 0 && r.onEmpty != nil {
+		go r.onEmpty() // el manager cierra la sala, no puede esperar al loop
+	}
+	return nil
+}
+
+// leaveTable saca al jugador del orden de la mesa. Si era su turno le toca al siguiente;
+// sin rivales la partida vuelve al lobby.
+func (r *Room) leaveTable(playerID string) {
+	if r.Status == "PLAYING" && r.State.CurrentPlayerID == playerID {
+		r.nextTurn()
+		r.resetTurnTimer()
+	}
+	order := make([]string, 0, len(r.PlayerOrder))
+	for _, id := range r.PlayerOrder {
+		if id != playerID {
+			order = append(order, id)
+		}
+	}
+	r.PlayerOrder = order
+	if len(order) < 2 {
+		r.reset()
+	}
 }
 
 // StartGame cambia el Status de la partida y prepara la primera ronda
 func (r *Room) StartGame(playerID string) error {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+	return r.exec(Action{Type: "start", PlayerID: playerID})
+}
 
+func (r *Room) startGame(playerID string) error {
 	p, exists := r.Players[playerID]
 	if !exists || !p.IsHost {
-		return errors.New("solo el host puede iniciar la partida")
+		return ErrNotHost
+	}
+
+	if r.Status == "PLAYING" {
+		return ErrGameStarted
 	}
 
 	if len(r.Players) < 1 {
-		return errors.New("no hay suficientes jugadores para comenzar")
+		return ErrNotEnoughPlayers
 	}
 
 	r.PlayerOrder = make([]string, 0, len(r.Players))
@@ -102,6 +222,9 @@ func (r *Room) StartGame(playerID string) error {
 		r.PlayerOrder = append(r.PlayerOrder, id)
 	}
 	r.Status = "PLAYING"
+	for _, p := range r.Players {
+		p.Ready = false
+	}
 	r.State = RoundState{
 		CurrentBetQuantity: 0,
 		CurrentBetFace: 0,
@@ -110,6 +233,8 @@ func (r *Room) StartGame(playerID string) error {
 	r.rollAllDice()
 
 	r.resetTurnTimer()
+	roundsStarted.Inc()
+	slog.Info("partida iniciada", "room_id", r.ID, "player_id", playerID, "players", len(r.PlayerOrder))
 
 	return nil
 }
@@ -121,11 +246,22 @@ func (r *Room) rollAllDice() {
 	}
 }
 
-// Reset devuelve la sala al estado de espera (Lobby)
-func (r *Room) Reset() {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+// Reset devuelve la sala al estado de espera (Lobby). Solo el host.
+func (r *Room) Reset(playerID string) error {
+	return r.exec(Action{Type: "reset", PlayerID: playerID})
+}
+
+// restart es el reset pedido por un jugador: el host se chequea en el loop, junto con el cambio
+func (r *Room) restart(playerID string) error {
+	p, exists := r.Players[playerID]
+	if !exists || !p.IsHost {
+		return ErrNotHost
+	}
+	r.reset()
+	return nil
+}
 
+func (r *Room) reset() {
 	r.Status = "WAITING"
 	r.LastResult = nil
 	
@@ -136,4 +272,109 @@ func (r *Room) Reset() {
 	r.PlayerOrder = nil
 
 	r.stopTurnTimer() // que no quede el timer corriendo
-}
\ No newline at end of file
+}
+
+// SetAccess define si la sala es privada y su contraseña opcional
+func (r *Room) SetAccess(private bool, password string) {
+	r.exec(Action{Type: "access", Private: private, Password: password})
+}
+
+func (r *Room) setAccess(private bool, password string) {
+	r.Private = private
+	r.passwordHash = nil
+	r.passwordSalt = nil
+	if private && password != "" {
+		r.passwordSalt = newPasswordSalt()
+		r.passwordHash = hashPassword(password, r.passwordSalt)
+	}
+}
+
+// HasPassword indica si la sala pide contraseña para entrar
+func (r *Room) HasPassword() bool {
+	has := false
+	r.read(func() { has = len(r.passwordHash) > 0 })
+	return has
+}
+
+// Admit valida la contraseña y habilita al jugador a conectarse a la sala
+func (r *Room) Admit(playerID string, password string) error {
+	return r.exec(Action{Type: "admit", PlayerID: playerID, Password: password})
+}
+
+func (r *Room) admit(playerID string, password string) error {
+	if len(r.passwordHash) > 0 && !checkPassword(password, r.passwordSalt, r.passwordHash) {
+		return ErrWrongPassword
+	}
+	r.admitted[playerID] = true
+	return nil
+}
+
+// Summary devuelve los datos de la sala para el listado publico
+func (r *Room) Summary() RoomSummary {
+	var summary RoomSummary
+	r.read(func() { summary = r.summary() })
+	return summary
+}
+
+func (r *Room) summary() RoomSummary {
+	return RoomSummary{
+		ID:          r.ID,
+		PlayerCount: len(r.Players),
+		Config:      r.Config,
+	}
+}
+
+// IsHost indica si el jugador es el host de la sala
+func (r *Room) IsHost(playerID string) bool {
+	isHost := false
+	r.read(func() {
+		p, ok := r.Players[playerID]
+		isHost = ok && p.IsHost
+	})
+	return isHost
+}
+
+// UpdateConfig cambia la configuracion de la sala, solo el host y fuera de una ronda en juego
+func (r *Room) UpdateConfig(playerID string, config GameConfig) error {
+	return r.exec(Action{Type: "config", PlayerID: playerID, Config: config})
+}
+
+func (r *Room) updateConfig(playerID string, config GameConfig) error {
+	p, ok := r.Players[playerID]
+	if !ok || !p.IsHost {
+		return ErrNotHost
+	}
+	if r.Status == "PLAYING" {
+		return ErrGameStarted
+	}
+
+	// El formulario del lobby no tiene cantidad de jugadores, 0 = mantener la actual
+	if config.MaxPlayers == 0 { config.MaxPlayers = r.Config.MaxPlayers }
+
+	// Validaciones de seguridad
+	if config.MaxPlayers < 2 { config.MaxPlayers = 2 }
+	if config.DicesAmount < 1 { config.DicesAmount = 5 }
+	if config.MinBetIncrement < 1 { config.MinBetIncrement = 1 }
+	if config.TurnDuration < 0 { config.TurnDuration = 0 }
+	if config.TurnDuration > MaxTurnDuration { config.TurnDuration = MaxTurnDuration }
+
+	r.Config = config
+	return nil
+}
+
+// SetReady marca si el jugador esta listo para comenzar
+func (r *Room) SetReady(playerID string, ready bool) error {
+	return r.exec(Action{Type: "ready", PlayerID: playerID, Ready: ready})
+}
+
+func (r *Room) setReady(playerID string, ready bool) error {
+	p, ok := r.Players[playerID]
+	if !ok {
+		return ErrNotAdmitted
+	}
+	if r.Status == "PLAYING" {
+		return ErrGameStarted
+	}
+	p.Ready = ready
+	return nil
+}
@@ -1,41 +1,413 @@
 package game
 
 import (
-	"errors"
+	"log/slog"
+	"sort"
 	"sync"
+	"time"
 )
 
+var (
+	ErrServerDraining = newError("server_draining", CategoryUnavailable)
+	ErrTooManyRooms = newError("too_many_rooms", CategoryUnavailable)
+	ErrRoomNotFound = newError("room_not_found", CategoryNotFound)
+)
+
+// emptyRoomGrace es cuanto puede quedar vacia una sala antes de cerrarla. Alcanza para que
+// el creador, que se sienta recien al abrir el WebSocket, llegue a entrar.
+const emptyRoomGrace = time.Minute
+
+// reclaimInterval es cada cuanto se buscan salas abandonadas (ver StartReclaiming)
+const reclaimInterval = 30 * time.Second
+
 // GameManager gestionara todas las salas activas del servidor
 type GameManager struct {
 	mutex sync.RWMutex
 	rooms map[string]*Room
+	creating map[string]bool // codigos reservados mientras se confirman en el broker
+	draining bool // true cuando el servidor se esta apagando
+	store Store // opcional, para persistir las salas al apagar
+	maxRooms int // 0 = sin limite
+	cluster *Cluster // reparte las salas entre instancias (ver cluster.go)
+	chatPolicy ChatPolicy // reglas del chat de las salas nuevas
+	onRoomLost func(roomID string) // avisa que una sala paso a otra instancia
+	onRoomClosed func(roomID string) // avisa que se cerro una sala abandonada
+	reclaimStop chan struct{} // detiene StartReclaiming
 }
 
 // NewGameManager inicializa un GameManager
 func NewGameManager() *GameManager {
 	return &GameManager{
 		rooms: make(map[string]*Room),
+		creating: make(map[string]bool),
+		chatPolicy: ChatPolicy{Scrollback: DefaultChatScrollback},
 	}
 }
 
 // CreateRoom crea una sala y la agrega al manager
-func (gm *GameManager) CreateRoom(id string, config GameConfig) *Room {
+func (gm *GameManager) CreateRoom(id string, config GameConfig) (*Room, error) {
 	gm.mutex.Lock()
-	defer gm.mutex.Unlock()
+	if gm.draining {
+		gm.mutex.Unlock()
+		return nil, ErrServerDraining
+	}
+	if gm.maxRooms > 0 && len(gm.rooms)+len(gm.creating) >= gm.maxRooms {
+		gm.mutex.Unlock()
+		return nil, ErrTooManyRooms
+	}
+	if _, exists := gm.rooms[id]; exists || gm.creating[id] {
+		gm.mutex.Unlock()
+		return nil, ErrRoomExists
+	}
+	gm.creating[id] = true
+	cluster := gm.cluster
+	policy := gm.chatPolicy
+	gm.mutex.Unlock()
 
-	newRoom := NewRoom(id, config)
-	gm.rooms[id]=newRoom
-	return newRoom
+	newRoom := newRoom(id, config)
+	newRoom.chatPolicy = policy
+	newRoom.onEmpty = gm.closeWhenEmpty(newRoom)
+	newRoom.start()
+
+	// el codigo tiene que estar libre en todas las instancias. Se consulta al broker sin
+	// el mutex, asi un broker lento no frena a GetRoom
+	err := cluster.attach(newRoom)
+
+	gm.mutex.Lock()
+	delete(gm.creating, id)
+	if err == nil {
+		gm.rooms[id] = newRoom
+	}
+	gm.mutex.Unlock()
+	if err != nil {
+		newRoom.Close()
+		return nil, err
+	}
+	slog.Info("sala creada", "room_id", id, "max_players", config.MaxPlayers, "dices", config.DicesAmount)
+	return newRoom, nil
 }
 
-// GetRoom busca una sala por ID
+// GetRoom busca una sala por ID. Si la sala es de otra instancia devuelve su copia local.
 func (gm *GameManager) GetRoom(id string) (*Room, error) {
 	gm.mutex.RLock()
-	defer gm.mutex.RUnlock()
+	room, exists := gm.rooms[id]
+	cluster := gm.cluster
+	gm.mutex.RUnlock()
+
+	if exists {
+		return room, nil
+	}
+	if cluster != nil {
+		return cluster.mirror(id)
+	}
+	return nil, ErrRoomNotFound
+}
+
+// DeleteRoom cierra una sala de esta instancia y la saca del manager
+func (gm *GameManager) DeleteRoom(id string) error {
+	gm.mutex.Lock()
+	room, exists := gm.rooms[id]
+	if exists {
+		delete(gm.rooms, id)
+	}
+	cluster := gm.cluster
+	gm.mutex.Unlock()
+
+	if !exists {
+		return ErrRoomNotFound
+	}
+	if cluster != nil {
+		cluster.disown(id)
+	}
+	room.Close()
+	slog.Info("sala eliminada", "room_id", id)
+	return nil
+}
+
+// closeWhenEmpty arma el aviso de la sala para cuando se va su ultimo jugador
+func (gm *GameManager) closeWhenEmpty(room *Room) func() {
+	return func() {
+		players := -1
+		room.read(func() { players = len(room.Players) })
+		if players == 0 { // puede haber entrado alguien mientras tanto
+			gm.reclaim(room, "empty")
+		}
+	}
+}
+
+// StartReclaiming revisa periodicamente las salas propias y cierra las que quedaron vacias
+// o pasaron idleTTL sin acciones de los jugadores. Se detiene con StartDraining, asi las
+// salas que quedan se guardan al apagar.
+func (gm *GameManager) StartReclaiming(idleTTL time.Duration) {
+	stop := make(chan struct{})
+	gm.mutex.Lock()
+	gm.reclaimStop = stop
+	gm.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(reclaimInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				gm.ReclaimIdle(idleTTL)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// ReclaimIdle cierra las salas propias vacias desde hace mas de emptyRoomGrace o sin
+// acciones desde hace mas de idleTTL. Devuelve cuantas cerro.
+func (gm *GameManager) ReclaimIdle(idleTTL time.Duration) int {
+	now := time.Now()
+	closed := 0
+	for _, room := range gm.Rooms() {
+		players := 0
+		var last time.Time
+		room.read(func() {
+			players = len(room.Players)
+			last = room.lastActivity
+		})
+		idle := now.Sub(last)
+		reason := ""
+		switch {
+		case players == 0 && idle > emptyRoomGrace:
+			reason = "empty"
+		case idle > idleTTL:
+			reason = "idle"
+		default:
+			continue
+		}
+		if gm.reclaim(room, reason) {
+			closed++
+		}
+	}
+	return closed
+}
+
+// reclaim saca la sala del manager, suelta su codigo y la cierra (con eso termina su loop y
+// las suscripciones). No hace nada si la sala ya no esta o el servidor se esta apagando.
+func (gm *GameManager) reclaim(room *Room, reason string) bool {
+	gm.mutex.Lock()
+	current := gm.rooms[room.ID] == room && !gm.draining
+	if current {
+		delete(gm.rooms, room.ID)
+	}
+	cluster := gm.cluster
+	notify := gm.onRoomClosed
+	gm.mutex.Unlock()
+
+	if !current {
+		return false
+	}
+	if cluster != nil {
+		cluster.disown(room.ID)
+	}
+	room.Close()
+	slog.Info("sala cerrada por inactividad", "room_id", room.ID, "reason", reason)
+	if notify != nil {
+		notify(room.ID)
+	}
+	return true
+}
+
+// OnRoomClosed registra quien se entera cuando se cierra una sala abandonada
+// (por ejemplo para desconectar a quien haya quedado mirando)
+func (gm *GameManager) OnRoomClosed(fn func(roomID string)) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	gm.onRoomClosed = fn
+}
 
+// OnRoomLost registra quien se entera cuando una sala propia pasa a otra instancia
+// (por ejemplo para cortar los WebSockets y que se reconecten a la duena nueva)
+func (gm *GameManager) OnRoomLost(fn func(roomID string)) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	gm.onRoomLost = fn
+}
+
+// loseRoom cierra la sala local que ahora es de otra instancia, sin soltar su codigo
+func (gm *GameManager) loseRoom(id string) {
+	gm.mutex.Lock()
 	room, exists := gm.rooms[id]
+	if exists {
+		delete(gm.rooms, id)
+	}
+	notify := gm.onRoomLost
+	gm.mutex.Unlock()
+
 	if !exists {
-		return nil, errors.New("sala no encontrada")
+		return
 	}
-	return room, nil
-}
\ No newline at end of file
+	room.Close()
+	if notify != nil {
+		notify(id)
+	}
+}
+
+// Instance devuelve el nombre de esta instancia ("" si no hay cluster)
+func (gm *GameManager) Instance() string {
+	gm.mutex.RLock()
+	cluster := gm.cluster
+	gm.mutex.RUnlock()
+	if cluster == nil {
+		return ""
+	}
+	return cluster.Instance()
+}
+
+// Owner devuelve la instancia duena de la sala ("" si no hay cluster o no existe)
+func (gm *GameManager) Owner(roomID string) string {
+	gm.mutex.RLock()
+	cluster := gm.cluster
+	gm.mutex.RUnlock()
+	if cluster == nil {
+		return ""
+	}
+	return cluster.Owner(roomID)
+}
+
+// Rooms devuelve una copia de la lista de salas activas de esta instancia
+func (gm *GameManager) Rooms() []*Room {
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+
+	rooms := make([]*Room, 0, len(gm.rooms))
+	for _, room := range gm.rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// PublicRooms lista las salas publicas que todavia estan esperando jugadores
+func (gm *GameManager) PublicRooms() []RoomSummary {
+	summaries := make([]RoomSummary, 0)
+	for _, room := range gm.Rooms() {
+		listed := false
+		var summary RoomSummary
+		room.read(func() {
+			listed = !room.Private && room.Status == "WAITING"
+			summary = room.summary()
+		})
+		if !listed {
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+
+	// Primero las salas con mas jugadores, asi se llenan antes
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].PlayerCount != summaries[j].PlayerCount {
+			return summaries[i].PlayerCount > summaries[j].PlayerCount
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+	return summaries
+}
+
+// StartDraining deja de aceptar salas nuevas, se usa al apagar el servidor
+func (gm *GameManager) StartDraining() {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	gm.draining = true
+	if gm.reclaimStop != nil {
+		close(gm.reclaimStop)
+		gm.reclaimStop = nil
+	}
+}
+
+// IsDraining indica si el servidor se esta apagando
+func (gm *GameManager) IsDraining() bool {
+	gm.mutex.RLock()
+	defer gm.mutex.RUnlock()
+	return gm.draining
+}
+
+// SetMaxRooms limita la cantidad de salas simultaneas (0 = sin limite)
+func (gm *GameManager) SetMaxRooms(max int) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	gm.maxRooms = max
+}
+
+// SetChatPolicy fija las reglas del chat de las salas que se creen de aca en adelante
+func (gm *GameManager) SetChatPolicy(policy ChatPolicy) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	gm.chatPolicy = policy
+}
+
+// SetStore configura donde se persisten las salas
+func (gm *GameManager) SetStore(store Store) {
+	gm.mutex.Lock()
+	defer gm.mutex.Unlock()
+	gm.store = store
+}
+
+// Restore carga las salas guardadas en el store (si hay uno configurado)
+func (gm *GameManager) Restore() (int, error) {
+	gm.mutex.RLock()
+	store := gm.store
+	cluster := gm.cluster
+	policy := gm.chatPolicy
+	gm.mutex.RUnlock()
+
+	if store == nil {
+		return 0, nil
+	}
+
+	snapshots, err := store.Load()
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	for _, snap := range snapshots {
+		room := restoreRoom(snap, policy)
+		room.do(func() { room.onEmpty = gm.closeWhenEmpty(room) })
+		// como en CreateRoom, el broker se consulta sin el mutex
+		if err := cluster.attach(room); err != nil {
+			slog.Warn("no se restaura la sala", "room_id", snap.ID, "err", err)
+			room.Close()
+			continue
+		}
+
+		gm.mutex.Lock()
+		gm.rooms[snap.ID] = room // se restaura al arrancar, antes de aceptar pedidos
+		gm.mutex.Unlock()
+		restored++
+	}
+	return restored, nil
+}
+
+// CheckStore indica si el store responde (nil si no hay store configurado)
+func (gm *GameManager) CheckStore() error {
+	gm.mutex.RLock()
+	store := gm.store
+	gm.mutex.RUnlock()
+
+	if store == nil {
+		return nil
+	}
+	return store.Ping()
+}
+
+// Persist guarda todas las salas en el store (si hay uno configurado)
+func (gm *GameManager) Persist() error {
+	gm.mutex.RLock()
+	store := gm.store
+	gm.mutex.RUnlock()
+
+	if store == nil {
+		return nil
+	}
+
+	rooms := gm.Rooms()
+	snapshots := make([]RoomSnapshot, 0, len(rooms))
+	for _, room := range rooms {
+		snapshots = append(snapshots, room.Snapshot())
+	}
+	return store.Save(snapshots)
+}
@@ -0,0 +1,81 @@
+package game
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoomClosesWhenLastPlayerLeaves(t *testing.T) {
+	gm := NewGameManager()
+	gm.SetMaxRooms(1)
+	closed := make(chan string, 1)
+	gm.OnRoomClosed(func(roomID string) { closed <- roomID })
+
+	room, err := gm.CreateRoom("abcde", GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates, _ := room.Subscribe()
+	if err := room.AddPlayer(&Player{ID: "ana", Name: "Ana"}); err != nil {
+		t.Fatal(err)
+	}
+	room.RemovePlayer("ana")
+
+	select {
+	case id := <-closed:
+		if id != "abcde" {
+			t.Errorf("se cerro %q", id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("la sala vacia no se cerro")
+	}
+	if _, err := gm.GetRoom("abcde"); !errors.Is(err, ErrRoomNotFound) {
+		t.Errorf("GetRoom = %v, se esperaba ErrRoomNotFound", err)
+	}
+
+	// la suscripcion se cierra, asi termina quien reenvia las actualizaciones
+	for range updates {
+	}
+
+	// y deja de contar para el limite de salas
+	if _, err := gm.CreateRoom("fghij", GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1}); err != nil {
+		t.Errorf("no se pudo crear otra sala: %v", err)
+	}
+}
+
+func TestReclaimIdle(t *testing.T) {
+	gm := NewGameManager()
+	config := GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1}
+
+	idle, _ := gm.CreateRoom("idle1", config)
+	idle.AddPlayer(&Player{ID: "ana", Name: "Ana"})
+	active, _ := gm.CreateRoom("activ", config)
+	active.AddPlayer(&Player{ID: "beto", Name: "Beto"})
+	empty, _ := gm.CreateRoom("empty", config)
+	fresh, _ := gm.CreateRoom("fresh", config) // vacia, pero recien creada
+
+	idle.do(func() { idle.lastActivity = time.Now().Add(-time.Hour) })
+	empty.do(func() { empty.lastActivity = time.Now().Add(-2 * emptyRoomGrace) })
+
+	if n := gm.ReclaimIdle(30 * time.Minute); n != 2 {
+		t.Errorf("ReclaimIdle cerro %d salas, se esperaban 2", n)
+	}
+	for _, id := range []string{"idle1", "empty"} {
+		if _, err := gm.GetRoom(id); err == nil {
+			t.Errorf("la sala %s sigue abierta", id)
+		}
+	}
+	for _, room := range []*Room{active, fresh} {
+		if _, err := gm.GetRoom(room.ID); err != nil {
+			t.Errorf("se cerro la sala %s: %v", room.ID, err)
+		}
+	}
+
+	// apagando no se cierra nada: las salas se guardan
+	gm.StartDraining()
+	active.do(func() { active.lastActivity = time.Now().Add(-time.Hour) })
+	if n := gm.ReclaimIdle(30 * time.Minute); n != 0 {
+		t.Errorf("ReclaimIdle cerro %d salas durante el apagado", n)
+	}
+}
@@ -0,0 +1,363 @@
+package game
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrAlreadyQueued = newError("already_queued", CategoryConflict)
+
+// ticketTTL es cuanto aguanta un ticket sin que la pantalla de espera consulte el estado
+// (lo hace cada 2 s). Si el jugador cerro la pagina se lo saca de la cola.
+const ticketTTL = 10 * time.Second
+
+// matchedTTL es cuanto se guarda la sala asignada a un jugador que no volvio a consultar
+const matchedTTL = time.Minute
+
+// MatchPrefs son las preferencias opcionales de quien busca partida rapida.
+// Un valor "Any" indica que al jugador le da igual.
+type MatchPrefs struct {
+	DicesAmount  int    // 0 = cualquiera
+	TurnDuration int    // -1 = cualquiera, 0 = sin limite
+	WildAces     string // "any", "on", "off"
+}
+
+// AnyPrefs devuelve preferencias sin restricciones
+func AnyPrefs() MatchPrefs {
+	return MatchPrefs{DicesAmount: 0, TurnDuration: -1, WildAces: "any"}
+}
+
+// compatible indica si dos preferencias pueden jugar en la misma sala
+func (a MatchPrefs) compatible(b MatchPrefs) bool {
+	if a.DicesAmount != 0 && b.DicesAmount != 0 && a.DicesAmount != b.DicesAmount {
+		return false
+	}
+	if a.TurnDuration != -1 && b.TurnDuration != -1 && a.TurnDuration != b.TurnDuration {
+		return false
+	}
+	if a.WildAces != "any" && b.WildAces != "any" && a.WildAces != b.WildAces {
+		return false
+	}
+	return true
+}
+
+// normalize aplica los mismos limites que updateConfig a la configuracion de la sala.
+// Lo que no tiene sentido (dados fuera de rango, comodines desconocidos) pasa a "cualquiera".
+func (a MatchPrefs) normalize() MatchPrefs {
+	if a.DicesAmount < 0 || a.DicesAmount > 6 {
+		a.DicesAmount = 0
+	}
+	if a.TurnDuration < -1 {
+		a.TurnDuration = 0
+	}
+	if a.TurnDuration > MaxTurnDuration {
+		a.TurnDuration = MaxTurnDuration
+	}
+	if a.WildAces != "on" && a.WildAces != "off" {
+		a.WildAces = "any"
+	}
+	return a
+}
+
+// merge combina dos preferencias compatibles quedandose con lo mas restrictivo
+func (a MatchPrefs) merge(b MatchPrefs) MatchPrefs {
+	if a.DicesAmount == 0 {
+		a.DicesAmount = b.DicesAmount
+	}
+	if a.TurnDuration == -1 {
+		a.TurnDuration = b.TurnDuration
+	}
+	if a.WildAces == "any" {
+		a.WildAces = b.WildAces
+	}
+	return a
+}
+
+// apply aplica las preferencias sobre una configuracion base
+func (a MatchPrefs) apply(config GameConfig) GameConfig {
+	if a.DicesAmount != 0 {
+		config.DicesAmount = a.DicesAmount
+	}
+	if a.TurnDuration != -1 {
+		config.TurnDuration = a.TurnDuration
+	}
+	switch a.WildAces {
+	case "on":
+		config.WildAces = true
+	case "off":
+		config.WildAces = false
+	}
+	return config
+}
+
+// MatchTicket representa a un jugador en la cola de partida rapida
+type MatchTicket struct {
+	PlayerID string
+	Name     string
+	Prefs    MatchPrefs
+	QueuedAt time.Time
+	SeenAt   time.Time // ultima consulta del estado (o cuando se asigno la sala)
+	RoomID   string    // se completa cuando se encuentra sala
+	matching bool      // ya esta en un grupo cuya sala se esta creando
+}
+
+// Matchmaker agrupa jugadores compatibles y les arma una sala automaticamente
+type Matchmaker struct {
+	mutex      sync.Mutex
+	manager    *GameManager
+	queue      []*MatchTicket
+	matched    map[string]*MatchTicket // tickets ya asignados, por PlayerID
+	defaults   GameConfig
+	newRoomID  func() string
+	MinPlayers int           // con este grupo se arranca en el momento
+	MaxWait    time.Duration // pasado este tiempo se arranca con al menos 2
+	stop       chan struct{}
+}
+
+// NewMatchmaker crea el servicio y arranca el chequeo periodico de esperas
+func NewMatchmaker(gm *GameManager, defaults GameConfig, newRoomID func() string, minPlayers int, maxWait time.Duration) *Matchmaker {
+	mm := &Matchmaker{
+		manager:    gm,
+		matched:    make(map[string]*MatchTicket),
+		defaults:   defaults,
+		newRoomID:  newRoomID,
+		MinPlayers: minPlayers,
+		MaxWait:    maxWait,
+		stop:       make(chan struct{}),
+	}
+	go mm.loop()
+	return mm
+}
+
+// loop revisa cada segundo si algun grupo supero el tiempo de espera
+// y descarta los tickets abandonados
+func (mm *Matchmaker) loop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			mm.mutex.Lock()
+			mm.expire(now)
+			groups := mm.takeGroups(now)
+			mm.mutex.Unlock()
+			mm.startMatches(groups)
+		case <-mm.stop:
+			return
+		}
+	}
+}
+
+// Stop detiene el chequeo periodico
+func (mm *Matchmaker) Stop() {
+	close(mm.stop)
+}
+
+// Enqueue agrega un jugador a la cola
+func (mm *Matchmaker) Enqueue(playerID, name string, prefs MatchPrefs) error {
+	mm.mutex.Lock()
+	for _, t := range mm.queue {
+		if t.PlayerID == playerID {
+			mm.mutex.Unlock()
+			return ErrAlreadyQueued
+		}
+	}
+	delete(mm.matched, playerID) // si vuelve a buscar se olvida la sala anterior
+
+	now := time.Now()
+	mm.queue = append(mm.queue, &MatchTicket{
+		PlayerID: playerID,
+		Name:     name,
+		Prefs:    prefs.normalize(),
+		QueuedAt: now,
+		SeenAt:   now,
+	})
+	groups := mm.takeGroups(now)
+	mm.mutex.Unlock()
+
+	// la sala se crea antes de responder, asi la pantalla de espera ya la encuentra
+	mm.startMatches(groups)
+	return nil
+}
+
+// Cancel saca a un jugador de la cola
+func (mm *Matchmaker) Cancel(playerID string) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	mm.removeFromQueue(playerID)
+}
+
+// Status devuelve el ticket del jugador y el largo de la cola. Si RoomID no esta
+// vacio ya tiene sala y el ticket se descarta (se informa una sola vez).
+// El tercer valor es false si el jugador no esta en la cola ni fue asignado.
+// Consultar el estado mantiene vivo el ticket (ver ticketTTL).
+func (mm *Matchmaker) Status(playerID string) (MatchTicket, int, bool) {
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+
+	if t, ok := mm.matched[playerID]; ok {
+		delete(mm.matched, playerID)
+		return *t, 0, true
+	}
+	for _, t := range mm.queue {
+		if t.PlayerID == playerID {
+			t.SeenAt = time.Now()
+			return *t, len(mm.queue), true
+		}
+	}
+	return MatchTicket{}, 0, false
+}
+
+// expire saca de la cola a quien dejo de consultar y olvida las salas asignadas
+// que nadie vino a buscar. Se llama con el mutex tomado.
+func (mm *Matchmaker) expire(now time.Time) {
+	queue := mm.queue[:0]
+	for _, t := range mm.queue {
+		if now.Sub(t.SeenAt) < ticketTTL {
+			queue = append(queue, t)
+		}
+	}
+	clear(mm.queue[len(queue):])
+	mm.queue = queue
+
+	for id, t := range mm.matched {
+		if now.Sub(t.SeenAt) >= matchedTTL {
+			delete(mm.matched, id)
+		}
+	}
+}
+
+// matchGroup es un grupo de la cola listo para jugar
+type matchGroup struct {
+	tickets []*MatchTicket
+	prefs   MatchPrefs
+}
+
+// takeGroups arma todos los grupos posibles con la cola actual y los marca para que no
+// entren en otro. Se llama con el mutex tomado; las salas se crean despues, sin el mutex
+// (ver startMatches).
+func (mm *Matchmaker) takeGroups(now time.Time) []matchGroup {
+	var groups []matchGroup
+	for {
+		tickets, prefs := mm.nextGroup(now)
+		if tickets == nil {
+			return groups
+		}
+		for _, t := range tickets {
+			t.matching = true
+		}
+		groups = append(groups, matchGroup{tickets: tickets, prefs: prefs})
+	}
+}
+
+// startMatches crea la sala de cada grupo sin el mutex (CreateRoom consulta al broker) y
+// despues anota el resultado. Si una sala no se pudo crear el grupo vuelve a la cola y
+// se reintenta en el proximo chequeo (por ejemplo si el servidor se esta apagando).
+func (mm *Matchmaker) startMatches(groups []matchGroup) {
+	for _, g := range groups {
+		roomID, unseated, err := mm.createMatch(g.tickets, g.prefs)
+
+		mm.mutex.Lock()
+		now := time.Now()
+		for _, t := range g.tickets {
+			t.matching = false
+			if err == nil {
+				t.RoomID = roomID
+				t.SeenAt = now
+				mm.matched[t.PlayerID] = t
+				mm.removeFromQueue(t.PlayerID)
+			}
+		}
+		if unseated != "" {
+			// sino el proximo intento vuelve a armar el mismo grupo
+			mm.removeFromQueue(unseated)
+		}
+		mm.mutex.Unlock()
+	}
+}
+
+// nextGroup busca, empezando por el que mas espero, un grupo listo para jugar
+func (mm *Matchmaker) nextGroup(now time.Time) ([]*MatchTicket, MatchPrefs) {
+	for i, first := range mm.queue {
+		if first.matching {
+			continue
+		}
+		group := []*MatchTicket{first}
+		prefs := first.Prefs
+
+		for _, t := range mm.queue[i+1:] {
+			if len(group) >= mm.defaults.MaxPlayers {
+				break
+			}
+			if !t.matching && prefs.compatible(t.Prefs) {
+				group = append(group, t)
+				prefs = prefs.merge(t.Prefs)
+			}
+		}
+
+		full := len(group) >= mm.MinPlayers
+		timedOut := len(group) >= 2 && now.Sub(first.QueuedAt) >= mm.MaxWait
+		if full || timedOut {
+			return group, prefs
+		}
+	}
+	return nil, MatchPrefs{}
+}
+
+// createMatch crea la sala, sienta al grupo y arranca la partida. Se llama sin el mutex:
+// de los tickets solo lee PlayerID y Name, que no cambian.
+// Si algo falla la sala se borra; si no se pudo sentar a un jugador se devuelve su ID.
+func (mm *Matchmaker) createMatch(group []*MatchTicket, prefs MatchPrefs) (string, string, error) {
+	room, err := mm.manager.CreateRoom(mm.newRoomID(), prefs.apply(mm.defaults))
+	if err != nil {
+		return "", "", err
+	}
+	room.SetAccess(true, "") // las salas de partida rapida no aparecen en el listado
+
+	for _, t := range group {
+		err := room.Admit(t.PlayerID, "")
+		if err == nil {
+			err = room.AddPlayer(&Player{ID: t.PlayerID, Name: uniqueName(room, t.Name)})
+		}
+		if err != nil {
+			slog.Warn("no se pudo sentar al jugador de la partida rapida", "room_id", room.ID, "player_id", t.PlayerID, "err", err)
+			mm.manager.DeleteRoom(room.ID)
+			return "", t.PlayerID, err
+		}
+	}
+
+	// El primero de la cola queda como host y arranca la partida
+	if err := room.StartGame(group[0].PlayerID); err != nil {
+		mm.manager.DeleteRoom(room.ID)
+		return "", "", err
+	}
+	return room.ID, "", nil
+}
+
+// uniqueName agrega un numero al nombre si otro del grupo ya lo usa ("Ana" -> "Ana 2")
+func uniqueName(room *Room, name string) string {
+	candidate := name
+	for i := 2; room.NameTaken(candidate); i++ {
+		suffix := fmt.Sprintf(" %d", i)
+		base := []rune(name)
+		if len(base)+len(suffix) > MaxNameLength {
+			base = base[:MaxNameLength-len(suffix)]
+		}
+		candidate = string(base) + suffix
+	}
+	return candidate
+}
+
+// removeFromQueue saca un ticket de la cola. Se llama con el mutex tomado.
+func (mm *Matchmaker) removeFromQueue(playerID string) {
+	for i, t := range mm.queue {
+		if t.PlayerID == playerID {
+			mm.queue = append(mm.queue[:i], mm.queue[i+1:]...)
+			return
+		}
+	}
+}
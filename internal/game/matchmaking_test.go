@@ -0,0 +1,114 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestMatchmaker arma un matchmaker que junta de a minPlayers
+func newTestMatchmaker(t *testing.T, minPlayers int) (*GameManager, *Matchmaker) {
+	t.Helper()
+	gm := NewGameManager()
+	var next atomic.Int64
+	newID := func() string { return fmt.Sprintf("m%04d", next.Add(1)) }
+	mm := NewMatchmaker(gm, GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1}, newID, minPlayers, time.Minute)
+	t.Cleanup(mm.Stop)
+	return gm, mm
+}
+
+func TestMatchmakerGroupsCompatiblePlayers(t *testing.T) {
+	gm, mm := newTestMatchmaker(t, 2)
+
+	if err := mm.Enqueue("ana", "Ana", MatchPrefs{DicesAmount: 3, TurnDuration: -1, WildAces: "any"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mm.Enqueue("beto", "Beto", MatchPrefs{DicesAmount: 4, TurnDuration: -1, WildAces: "any"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, queued, _ := mm.Status("beto"); queued != 2 {
+		t.Fatalf("con preferencias incompatibles la cola deberia tener 2, tiene %d", queued)
+	}
+
+	// Carla acepta cualquier cosa: juega con Ana, que espera desde antes
+	if err := mm.Enqueue("carla", "Ana", AnyPrefs()); err != nil {
+		t.Fatal(err)
+	}
+	ana, _, _ := mm.Status("ana")
+	carla, _, _ := mm.Status("carla")
+	if ana.RoomID == "" || ana.RoomID != carla.RoomID {
+		t.Fatalf("Ana y Carla deberian compartir sala: %q %q", ana.RoomID, carla.RoomID)
+	}
+
+	room, err := gm.GetRoom(ana.RoomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := room.Snapshot()
+	if snap.Status != "PLAYING" || snap.Config.DicesAmount != 3 || !snap.Private {
+		t.Errorf("sala = %s, %d dados, privada %t", snap.Status, snap.Config.DicesAmount, snap.Private)
+	}
+	if !room.NameTaken("Ana 2") {
+		t.Error("el segundo Ana deberia llamarse \"Ana 2\"")
+	}
+	if !room.IsHost("ana") {
+		t.Error("el que mas espero deberia ser el host")
+	}
+}
+
+func TestMatchmakerRejectsDuplicateTickets(t *testing.T) {
+	_, mm := newTestMatchmaker(t, 4)
+
+	if err := mm.Enqueue("ana", "Ana", AnyPrefs()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mm.Enqueue("ana", "Ana", AnyPrefs()); !errors.Is(err, ErrAlreadyQueued) {
+		t.Errorf("Enqueue repetido = %v, se esperaba ErrAlreadyQueued", err)
+	}
+}
+
+func TestMatchPrefsNormalize(t *testing.T) {
+	cases := []struct {
+		in, want MatchPrefs
+	}{
+		{MatchPrefs{TurnDuration: -1, WildAces: "any"}, MatchPrefs{TurnDuration: -1, WildAces: "any"}},
+		{MatchPrefs{TurnDuration: -40, WildAces: "on"}, MatchPrefs{TurnDuration: 0, WildAces: "on"}},
+		{MatchPrefs{TurnDuration: 1 << 40, WildAces: "x"}, MatchPrefs{TurnDuration: MaxTurnDuration, WildAces: "any"}},
+		{MatchPrefs{DicesAmount: 9, TurnDuration: 30, WildAces: "off"}, MatchPrefs{DicesAmount: 0, TurnDuration: 30, WildAces: "off"}},
+	}
+	for _, c := range cases {
+		if got := c.in.normalize(); got != c.want {
+			t.Errorf("normalize(%+v) = %+v, se esperaba %+v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestMatchmakerConcurrentEnqueue(t *testing.T) {
+	gm, mm := newTestMatchmaker(t, 2)
+
+	const players = 20
+	var wg sync.WaitGroup
+	for i := 0; i < players; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("p%02d", i)
+			if err := mm.Enqueue(id, "Jugador", AnyPrefs()); err != nil {
+				t.Error(err)
+			}
+			mm.Status(id)
+		}(i)
+	}
+	wg.Wait()
+
+	seated := 0
+	for _, room := range gm.Rooms() {
+		seated += len(room.Snapshot().Players)
+	}
+	if seated != players {
+		t.Errorf("se sentaron %d jugadores, se esperaban %d", seated, players)
+	}
+}
@@ -0,0 +1,12 @@
+package game
+
+import "dados-mentirosos/internal/metrics"
+
+// Metricas del juego (se exponen en /metrics). Solo cuenta la instancia duena de
+// cada sala, las copias de otras instancias no aplican acciones.
+var (
+	roundsStarted  = metrics.NewCounter("dados_rounds_started_total", "Rondas iniciadas (comienzo de partida o siguiente ronda).")
+	roundsFinished = metrics.NewCounter("dados_rounds_finished_total", "Rondas terminadas con un llamado a mentiroso.")
+	challenges     = metrics.NewCounter("dados_challenges_total", "Llamados a mentiroso por resultado: bluff_caught (la apuesta era falsa) o bet_held (la apuesta se cumplia).", "outcome")
+	turnTimeouts   = metrics.NewCounter("dados_turn_timeouts_total", "Turnos vencidos por tiempo, con apuesta automatica.")
+)
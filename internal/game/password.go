@@ -0,0 +1,46 @@
+package game
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+)
+
+// Las contraseñas de sala se guardan como PBKDF2-SHA256 con una sal aleatoria por sala.
+// El hash viaja en los snapshots (store y broker), asi que no tiene que servir para
+// probar contraseñas comunes de a millones.
+const (
+	passwordSaltSize   = 16
+	passwordIterations = 100_000
+	passwordKeySize    = 32
+)
+
+// newPasswordSalt genera la sal de una contraseña nueva
+func newPasswordSalt() []byte {
+	salt := make([]byte, passwordSaltSize)
+	rand.Read(salt)
+	return salt
+}
+
+// hashPassword deriva el hash de la contraseña con la sal de la sala
+func hashPassword(password string, salt []byte) []byte {
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeySize)
+	if err != nil {
+		return nil // no pasa con estos parametros
+	}
+	return key
+}
+
+// checkPassword compara en tiempo constante. Las salas guardadas antes de usar sal
+// tienen un sha256 sin sal y se siguen aceptando.
+func checkPassword(password string, salt, hash []byte) bool {
+	var got []byte
+	if len(salt) == 0 {
+		sum := sha256.Sum256([]byte(password))
+		got = sum[:]
+	} else {
+		got = hashPassword(password, salt)
+	}
+	return got != nil && subtle.ConstantTimeCompare(got, hash) == 1
+}
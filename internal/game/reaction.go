@@ -0,0 +1,45 @@
+package game
+
+var (
+	ErrInvalidReaction = newError("invalid_reaction", CategoryInvalid)
+	// lo devuelven los handlers cuando el jugador reacciona mas rapido que reaction_rate
+	ErrReactionRateLimited = newError("reaction_rate_limited", CategoryConflict)
+)
+
+// Reactions son los emotes que se pueden mandar a la mesa durante la partida
+var Reactions = []string{"🤔", "😂", "🤥", "👏"}
+
+// Reaction es un emote que un jugador manda a la mesa. No queda en el estado de la
+// sala: solo viaja con la actualizacion "react" y cada cliente lo muestra un rato.
+type Reaction struct {
+	PlayerID string `json:"player_id"`
+	Emoji    string `json:"emoji"`
+}
+
+// React manda una reaccion del jugador a toda la sala
+func (r *Room) React(playerID, emoji string) error {
+	return r.exec(Action{Type: "react", PlayerID: playerID, Text: emoji})
+}
+
+func (r *Room) react(playerID, emoji string) error {
+	if _, ok := r.Players[playerID]; !ok {
+		return ErrPlayerNotFound
+	}
+	if r.Status != "PLAYING" {
+		return ErrNotPlaying
+	}
+	if !validReaction(emoji) {
+		return ErrInvalidReaction.With("reactions", Reactions)
+	}
+	r.reaction = &Reaction{PlayerID: playerID, Emoji: emoji}
+	return nil
+}
+
+func validReaction(emoji string) bool {
+	for _, e := range Reactions {
+		if e == emoji {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,18 @@
 package game
 
 import (
-	"errors"
+	"log/slog"
+	"slices"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	ErrNotYourTurn  = errors.New("no es tu turno")
-	ErrInvalidBet   = errors.New("la apuesta debe ser mayor a la actual")
-	ErrNoBetMade    = errors.New("no hay apuesta previa para llamar mentiroso")
+	ErrNotYourTurn  = newError("not_your_turn", CategoryConflict)
+	ErrInvalidBet   = newError("invalid_bet", CategoryInvalid)
+	ErrNoBetMade    = newError("no_bet_made", CategoryConflict)
+	ErrNotPlaying   = newError("not_playing", CategoryConflict)
+	ErrNotFinished  = newError("not_finished", CategoryConflict)
 )
 
 // rollDice genera nuevos numeros para un jugador.
@@ -24,19 +28,20 @@ func (r *Room) rollDice(p *Player) {
 
 // PlaceBet maneja la logica de realizar apuestas
 func (r *Room) PlaceBet(playerID string, quantity int, face int) error {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+	return r.exec(Action{Type: "bet", PlayerID: playerID, Quantity: quantity, Face: face})
+}
 
+func (r *Room) placeBet(playerID string, quantity int, face int) error {
 	if r.Status != "PLAYING" {
-		return errors.New("la partida no está en curso")
+		return ErrNotPlaying
 	}
 
 	if r.State.CurrentPlayerID != playerID {
-		return ErrNotYourTurn
+		return ErrNotYourTurn.With("current_player_id", r.State.CurrentPlayerID)
 	}
 
 	if !r.isValidBet(quantity, face) {
-		return ErrInvalidBet
+		return ErrInvalidBet.With("min_quantity", r.minBetQuantity())
 	}
 
 	// Actualizar estado de la apuesta
@@ -61,12 +66,35 @@ func (r *Room) isValidBet(qty int, face int) bool {
 	return false
 }
 
+// minBetQuantity es la menor cantidad que se puede apostar ahora
+func (r *Room) minBetQuantity() int {
+	if r.State.CurrentBetQuantity == 0 {
+		return 1
+	}
+	return r.State.CurrentBetQuantity + r.Config.MinBetIncrement
+}
+
 // CallLiar termina el juego inmediatamente y retorna el resultado.
 func (r *Room) CallLiar(accuserPlayerID string) (*GameResult, error) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+	if err := r.exec(Action{Type: "liar", PlayerID: accuserPlayerID}); err != nil {
+		return nil, err
+	}
+	var result *GameResult
+	r.read(func() {
+		if r.LastResult != nil {
+			copied := *r.LastResult
+			result = &copied
+		}
+	})
+	return result, nil
+}
+
+func (r *Room) callLiar(accuserPlayerID string) (*GameResult, error) {
+	if r.Status != "PLAYING" {
+		return nil, ErrNotPlaying
+	}
 	if r.State.CurrentPlayerID != accuserPlayerID {
-		return nil, ErrNotYourTurn
+		return nil, ErrNotYourTurn.With("current_player_id", r.State.CurrentPlayerID)
 	}
 	if r.State.CurrentBetQuantity == 0 {
 		return nil, ErrNoBetMade
@@ -120,6 +148,13 @@ func (r *Room) CallLiar(accuserPlayerID string) (*GameResult, error) {
 	}
 
 	r.LastResult = result
+	roundsFinished.Inc()
+	outcome := "bet_held"
+	if isLiar {
+		outcome = "bluff_caught"
+	}
+	challenges.Inc(outcome)
+	slog.Info("ronda terminada", "room_id", r.ID, "player_id", accuserPlayerID, "bluffer_id", blufferID, "outcome", outcome)
 
 	return result, nil
 }
@@ -159,27 +194,45 @@ func (r *Room) resetTurnTimer() {
 	duration := time.Duration(r.Config.TurnDuration) * time.Second
 	r.TurnDeadline = time.Now().Add(duration)
 
+	// el timer no toca la sala: encola el timeout en el loop. Si el turno cambio
+	// mientras tanto (turnSeq distinto) el comando se descarta.
+	seq := r.turnSeq
+	armedTimers.Add(1)
 	r.TurnTimer = time.AfterFunc(duration, func() {
-		r.handleTimeout()
+		armedTimers.Add(-1)
+		r.update("timeout", func() error {
+			if seq != r.turnSeq {
+				return errNoChange
+			}
+			return r.handleTimeout()
+		})
 	})
 }
 
+// armedTimers cuenta los timers de turno que todavia no vencieron, en todas las salas
+var armedTimers atomic.Int64
+
+// ArmedTurnTimers devuelve cuantos timers de turno estan corriendo en esta instancia
+func ArmedTurnTimers() int {
+	return int(armedTimers.Load())
+}
+
 // stopTurnTimer detiene el reloj
 func (r *Room) stopTurnTimer() {
 	if r.TurnTimer != nil {
-		r.TurnTimer.Stop()
+		if r.TurnTimer.Stop() {
+			armedTimers.Add(-1) // no llego a dispararse
+		}
 		r.TurnTimer = nil
 	}
+	r.turnSeq++
 	r.TurnDeadline = time.Time{} // resetear fecha
 }
 
-// handleTimeout se ejecuta cuando se termina el tiempo
-func (r *Room) handleTimeout() {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-
+// handleTimeout se ejecuta en el loop cuando se termina el tiempo
+func (r *Room) handleTimeout() error {
 	if r.Status != "PLAYING" {
-		return
+		return errNoChange
 	}
 
 	currentPlayer := r.State.CurrentPlayerID
@@ -198,23 +251,33 @@ func (r *Room) handleTimeout() {
 
 	r.nextTurn()
 	r.resetTurnTimer()
+	turnTimeouts.Inc()
+	slog.Info("turno vencido, apuesta automatica", "room_id", r.ID, "player_id", currentPlayer, "quantity", quantity, "face", face)
+	return nil
+}
 
-	if r.OnUpdate != nil {
-		go r.OnUpdate(r.ID) // goroutine aparte para no bloquear el mutex
-	}
+// NextRound inicia una nueva ronda donde el perdedor anterior es quien inicia.
+// Solo la puede pedir el host desde la pantalla de resultados (sala FINISHED).
+func (r *Room) NextRound(playerID string) error {
+	return r.exec(Action{Type: "next-round", PlayerID: playerID})
 }
 
-// NextRound inicia una nueva ronda donde el perdedor anterior es quien inicia
-func (r *Room) NextRound() {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
+func (r *Room) nextRound(playerID string) error {
+	p, exists := r.Players[playerID]
+	if !exists || !p.IsHost {
+		return ErrNotHost
+	}
+	if r.Status != "FINISHED" {
+		return ErrNotFinished
+	}
 
 	startPlayerID := ""
 	if r.LastResult != nil {
 		startPlayerID = r.LastResult.LoserID
 	}
 
-	if startPlayerID == "" && len(r.PlayerOrder) > 0 {
+	// si el perdedor ya no esta en la mesa arranca el primero
+	if !slices.Contains(r.PlayerOrder, startPlayerID) && len(r.PlayerOrder) > 0 {
 		startPlayerID = r.PlayerOrder[0]
 	}
 
@@ -229,4 +292,6 @@ func (r *Room) NextRound() {
 
 	r.rollAllDice() // volver a tirar los dados
 	r.resetTurnTimer() // resetear reloj
+	roundsStarted.Inc()
+	return nil
 }
\ No newline at end of file
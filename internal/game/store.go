@@ -0,0 +1,200 @@
+package game
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Store guarda y recupera el estado de las salas entre reinicios del servidor
+type Store interface {
+	Save(rooms []RoomSnapshot) error
+	Load() ([]RoomSnapshot, error)
+	Ping() error // indica si se puede guardar (lo usa /readyz)
+}
+
+// RoomSnapshot es la copia serializable de una sala (sin mutex, timers ni callbacks)
+type RoomSnapshot struct {
+	ID           string
+	Players      []Player
+	PlayerOrder  []string
+	Config       GameConfig
+	State        RoundState
+	Status       string
+	LastResult   *GameResult
+	Private      bool
+	PasswordHash []byte
+	PasswordSalt []byte `json:",omitempty"` // vacio en salas guardadas antes de usar sal
+	Admitted     []string
+	TurnDeadline time.Time     `json:",omitempty"`
+	CreatedAt    time.Time     `json:",omitempty"`
+	Kicked       []string      `json:",omitempty"`
+	Chat         []ChatMessage `json:",omitempty"`
+	Muted        []string      `json:",omitempty"`
+}
+
+// Snapshot copia el estado de la sala desde su loop
+func (r *Room) Snapshot() RoomSnapshot {
+	var snap RoomSnapshot
+	r.read(func() { snap = r.snapshot() })
+	return snap
+}
+
+func (r *Room) snapshot() RoomSnapshot {
+	players := make([]Player, 0, len(r.Players))
+	for _, p := range r.Players {
+		players = append(players, *p)
+	}
+
+	admitted := make([]string, 0, len(r.admitted))
+	for id := range r.admitted {
+		admitted = append(admitted, id)
+	}
+	var kicked []string
+	for id := range r.kicked {
+		kicked = append(kicked, id)
+	}
+	var muted []string
+	for id := range r.muted {
+		muted = append(muted, id)
+	}
+
+	return RoomSnapshot{
+		ID:           r.ID,
+		Players:      players,
+		PlayerOrder:  append([]string(nil), r.PlayerOrder...),
+		Config:       r.Config,
+		State:        r.State,
+		Status:       r.Status,
+		LastResult:   r.LastResult,
+		Private:      r.Private,
+		PasswordHash: r.passwordHash,
+		PasswordSalt: r.passwordSalt,
+		Admitted:     admitted,
+		TurnDeadline: r.TurnDeadline,
+		CreatedAt:    r.CreatedAt,
+		Kicked:       kicked,
+		Chat:         append([]ChatMessage(nil), r.chat...),
+		Muted:        muted,
+	}
+}
+
+// redacted devuelve una copia del snapshot para otra instancia: sin la contraseña y con
+// los dados sin revelar tapados (quedan en 0, se conserva la cantidad) salvo los de los
+// jugadores de visible
+func (snap RoomSnapshot) redacted(visible map[string]bool) RoomSnapshot {
+	snap.PasswordHash = nil
+	snap.PasswordSalt = nil
+	if snap.Status == "FINISHED" {
+		return snap // los dados ya se revelaron
+	}
+	players := make([]Player, len(snap.Players))
+	for i, p := range snap.Players {
+		if !visible[p.ID] {
+			p.Dice = make([]Dice, len(p.Dice))
+		}
+		players[i] = p
+	}
+	snap.Players = players
+	return snap
+}
+
+// restoreRoom reconstruye una sala a partir de un snapshot guardado
+func restoreRoom(snap RoomSnapshot, policy ChatPolicy) *Room {
+	room := newRoom(snap.ID, snap.Config)
+	room.chatPolicy = policy
+	room.load(snap)
+
+	// si la partida estaba en curso el turno vuelve a empezar con el tiempo completo
+	if room.Status == "PLAYING" {
+		room.resetTurnTimer()
+	}
+	room.start()
+	return room
+}
+
+// load reemplaza el estado de la sala por el del snapshot (sin tocar timers)
+func (r *Room) load(snap RoomSnapshot) {
+	r.Config = snap.Config
+	r.Players = make(map[string]*Player, len(snap.Players))
+	for i := range snap.Players {
+		p := snap.Players[i]
+		r.Players[p.ID] = &p
+	}
+	r.PlayerOrder = snap.PlayerOrder
+	r.State = snap.State
+	r.Status = snap.Status
+	r.LastResult = snap.LastResult
+	r.Private = snap.Private
+	r.passwordHash = snap.PasswordHash
+	r.passwordSalt = snap.PasswordSalt
+	r.admitted = make(map[string]bool, len(snap.Admitted))
+	for _, id := range snap.Admitted {
+		r.admitted[id] = true
+	}
+	r.TurnDeadline = snap.TurnDeadline
+	r.kicked = make(map[string]bool, len(snap.Kicked))
+	for _, id := range snap.Kicked {
+		r.kicked[id] = true
+	}
+	r.chat = snap.Chat
+	r.muted = make(map[string]bool, len(snap.Muted))
+	for _, id := range snap.Muted {
+		r.muted[id] = true
+	}
+	if !snap.CreatedAt.IsZero() {
+		r.CreatedAt = snap.CreatedAt
+	}
+}
+
+// FileStore guarda las salas como JSON en un archivo local
+type FileStore struct {
+	Path string
+}
+
+// NewFileStore crea un store que persiste en el archivo indicado
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// Save escribe las salas en un archivo temporal y luego lo renombra para no dejarlo a medias
+func (fs *FileStore) Save(rooms []RoomSnapshot) error {
+	data, err := json.MarshalIndent(rooms, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := fs.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.Path)
+}
+
+// Ping verifica que exista el directorio del archivo y que se pueda escribir en el
+func (fs *FileStore) Ping() error {
+	f, err := os.CreateTemp(filepath.Dir(fs.Path), ".ping-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Load lee las salas guardadas, si el archivo no existe no hay nada que restaurar
+func (fs *FileStore) Load() ([]RoomSnapshot, error) {
+	data, err := os.ReadFile(fs.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rooms []RoomSnapshot
+	if err := json.Unmarshal(data, &rooms); err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
@@ -2,7 +2,6 @@ package game
 
 import (
 	"math/rand"
-	"sync"
 	"time"
 )
 
@@ -15,6 +14,7 @@ type Player struct {
 	Name string
 	Dice []Dice
 	IsHost bool
+	Ready bool // marcado en el lobby, se limpia al comenzar la partida
 }
 
 // Configuraciones de la sala
@@ -34,12 +34,10 @@ type RoundState struct {
 	CurrentBetFace int // cada de la apuesta
 }
 
-type UpdateCallback func(roomID string)
-
-// estructura de la sala
+// estructura de la sala. El estado solo lo toca el loop de la sala (ver engine.go),
+// desde afuera se usa a traves de sus metodos.
 type Room struct {
 	ID string
-	Mutex sync.RWMutex
 	Players map[string]*Player // lista de jugadores
 	PlayerOrder []string // lista para saber el orden de la mesa
 	Config GameConfig
@@ -49,7 +47,41 @@ type Room struct {
 	LastResult *GameResult
 	TurnTimer *time.Timer // reloj interno
 	TurnDeadline time.Time // hora exacta
-	OnUpdate UpdateCallback // funcion para actualizar pantallas
+	turnSeq int // cambia con cada timer, asi un timer viejo no hace nada
+	Private bool // las salas privadas no aparecen en el listado publico
+	passwordHash []byte // hash de la contraseña (vacio = sin contraseña)
+	passwordSalt []byte // sal aleatoria de la sala para el hash (ver password.go)
+	admitted map[string]bool // jugadores que pasaron la validacion para entrar a una sala privada
+	kicked map[string]bool // jugadores expulsados por un administrador
+	CreatedAt time.Time
+	lastActivity time.Time // ultima accion de un jugador (los timeouts no cuentan, ver ReclaimIdle)
+	onEmpty func() // la pone el manager: se llama cuando se va el ultimo jugador
+
+	// chat de la sala (ver chat.go)
+	chat []ChatMessage // ultimos mensajes, del mas viejo al mas nuevo
+	muted map[string]bool // jugadores que el host silencio
+	chatPolicy ChatPolicy
+	reaction *Reaction // ultima reaccion, viaja con la actualizacion "react" (ver reaction.go)
+
+	// loop de la sala
+	cmds chan command
+	closed chan struct{}
+	stopping bool
+	subscribers map[int]chan RoomUpdate
+	nextSubID int
+
+	// si no es nil la sala es una copia de otra instancia: las acciones se reenvian
+	relay RelayFunc
+	// en las copias, se llama antes de armar la vista de un jugador para que la duena
+	// mande sus dados (ver Cluster.watchPlayer)
+	viewer func(playerID string)
+}
+
+// RoomSummary es lo que se muestra de una sala publica en el listado del home
+type RoomSummary struct {
+	ID          string
+	PlayerCount int
+	Config      GameConfig
 }
 
 // GameResult contiene los datos finales para mostrar en la pantalla de resultados
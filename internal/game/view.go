@@ -0,0 +1,187 @@
+package game
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// SeatView es lo que cualquiera en la mesa puede ver de un jugador
+type SeatView struct {
+	ID        string
+	Name      string
+	IsHost    bool
+	Ready     bool
+	IsTurn    bool
+	DiceCount int
+	Dice      []Dice // solo despues de la revelacion (sala FINISHED)
+	Muted     bool   // el host lo silencio en el chat
+}
+
+// SpectatorView es el estado publico de la sala: nunca incluye dados sin revelar.
+// Se arma dentro del loop de la sala (ver engine.go) y no comparte memoria con ella,
+// asi se puede leer desde cualquier goroutine.
+type SpectatorView struct {
+	RoomID  string
+	Status  string
+	Config  GameConfig
+	Seats   []SeatView // en el orden de la mesa
+	Private bool
+
+	// Ronda en curso (solo con Status PLAYING)
+	CurrentPlayerID    string
+	CurrentPlayerName  string
+	CurrentBetQuantity int
+	CurrentBetFace     int
+	LastBetPlayerID    string
+	LastBetPlayerName  string
+	TurnDeadline       time.Time // vacio si el turno no tiene limite
+	SecondsLeft        int       // redondeado para arriba, asi no muestra 0 antes de tiempo
+
+	// Resultado de la ronda (solo con Status FINISHED)
+	Result *GameResult
+}
+
+// TurnPercent es cuanto queda del turno, de 0 a 100 (para la barra del tiempo)
+func (v SpectatorView) TurnPercent() int {
+	if v.Config.TurnDuration <= 0 {
+		return 0
+	}
+	return min(100, v.SecondsLeft*100/v.Config.TurnDuration)
+}
+
+// PlayerView es lo que ve un jugador sentado: lo publico mas sus propios dados
+type PlayerView struct {
+	SpectatorView
+	MyID     string
+	MyName   string
+	MyDice   []Dice
+	IsHost   bool
+	IsMyTurn bool
+}
+
+// Opponents devuelve los asientos de los demas jugadores
+func (v PlayerView) Opponents() []SeatView {
+	opponents := make([]SeatView, 0, len(v.Seats))
+	for _, s := range v.Seats {
+		if s.ID != v.MyID {
+			opponents = append(opponents, s)
+		}
+	}
+	return opponents
+}
+
+// SpectatorView arma la vista publica de la sala
+func (r *Room) SpectatorView() SpectatorView {
+	var view SpectatorView
+	r.read(func() { view = r.spectatorView() })
+	return view
+}
+
+// PlayerView arma la vista de un jugador sentado en la sala
+func (r *Room) PlayerView(playerID string) (PlayerView, error) {
+	if r.viewer != nil {
+		r.viewer(playerID)
+	}
+	var view PlayerView
+	var err error = ErrRoomClosed
+	r.read(func() { view, err = r.playerView(playerID) })
+	return view, err
+}
+
+func (r *Room) playerView(playerID string) (PlayerView, error) {
+	me, ok := r.Players[playerID]
+	if !ok {
+		return PlayerView{}, ErrNotAdmitted
+	}
+
+	view := PlayerView{
+		SpectatorView: r.spectatorView(),
+		MyID:          me.ID,
+		MyName:        me.Name,
+		MyDice:        append([]Dice{}, me.Dice...),
+		IsHost:        me.IsHost,
+	}
+	view.IsMyTurn = view.Status == "PLAYING" && view.CurrentPlayerID == playerID
+	return view, nil
+}
+
+// spectatorView se llama desde el loop de la sala
+func (r *Room) spectatorView() SpectatorView {
+	revealed := r.Status == "FINISHED"
+	playing := r.Status == "PLAYING"
+
+	view := SpectatorView{
+		RoomID:  r.ID,
+		Status:  r.Status,
+		Config:  r.Config,
+		Seats:   make([]SeatView, 0, len(r.Players)),
+		Private: r.Private,
+	}
+
+	for _, id := range r.seatOrder() {
+		p := r.Players[id]
+		seat := SeatView{
+			ID:        p.ID,
+			Name:      p.Name,
+			IsHost:    p.IsHost,
+			Ready:     p.Ready,
+			IsTurn:    playing && p.ID == r.State.CurrentPlayerID,
+			DiceCount: len(p.Dice),
+			Muted:     r.muted[p.ID],
+		}
+		if revealed {
+			seat.Dice = append([]Dice{}, p.Dice...)
+		}
+		view.Seats = append(view.Seats, seat)
+	}
+
+	if playing {
+		view.CurrentPlayerID = r.State.CurrentPlayerID
+		view.CurrentBetQuantity = r.State.CurrentBetQuantity
+		view.CurrentBetFace = r.State.CurrentBetFace
+		view.LastBetPlayerID = r.State.LastBetPlayerID
+		if p, ok := r.Players[r.State.CurrentPlayerID]; ok {
+			view.CurrentPlayerName = p.Name
+		}
+		if p, ok := r.Players[r.State.LastBetPlayerID]; ok {
+			view.LastBetPlayerName = p.Name
+		}
+		view.TurnDeadline = r.TurnDeadline
+		if !r.TurnDeadline.IsZero() {
+			if remaining := time.Until(r.TurnDeadline); remaining > 0 {
+				view.SecondsLeft = int(math.Ceil(remaining.Seconds()))
+			}
+		}
+	}
+
+	if revealed && r.LastResult != nil {
+		result := *r.LastResult
+		view.Result = &result
+	}
+	return view
+}
+
+// seatOrder devuelve los IDs en el orden de la mesa; los que no estan en
+// PlayerOrder (lobby o recien llegados) van al final ordenados por nombre
+func (r *Room) seatOrder() []string {
+	ids := make([]string, 0, len(r.Players))
+	seated := make(map[string]bool, len(r.PlayerOrder))
+	for _, id := range r.PlayerOrder {
+		if _, ok := r.Players[id]; ok && !seated[id] {
+			ids = append(ids, id)
+			seated[id] = true
+		}
+	}
+
+	var rest []string
+	for id := range r.Players {
+		if !seated[id] {
+			rest = append(rest, id)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		return r.Players[rest[i]].Name < r.Players[rest[j]].Name
+	})
+	return append(ids, rest...)
+}
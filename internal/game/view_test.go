@@ -0,0 +1,115 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+// newTestGame arma una sala con Ana (host) y Beto con la partida en curso
+func newTestGame(t *testing.T) *Room {
+	t.Helper()
+	return newTestGameWith(t, GameConfig{DicesAmount: 5, MaxPlayers: 4, MinBetIncrement: 1})
+}
+
+// newTestGameWith es newTestGame con otra configuracion (por ejemplo con timer de turno)
+func newTestGameWith(t *testing.T, config GameConfig) *Room {
+	t.Helper()
+	room := newRoom("abcde", config)
+	room.start()
+	t.Cleanup(room.Close)
+
+	for _, p := range []*Player{{ID: "ana", Name: "Ana"}, {ID: "beto", Name: "Beto"}} {
+		if err := room.AddPlayer(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := room.StartGame("ana"); err != nil {
+		t.Fatal(err)
+	}
+	return room
+}
+
+// dice devuelve los dados reales de cada jugador
+func dice(room *Room) map[string][]Dice {
+	all := make(map[string][]Dice)
+	for _, p := range room.Snapshot().Players {
+		all[p.ID] = p.Dice
+	}
+	return all
+}
+
+func TestViewsHideDiceWhilePlaying(t *testing.T) {
+	room := newTestGame(t)
+	real := dice(room)
+
+	for _, seat := range room.SpectatorView().Seats {
+		if seat.Dice != nil {
+			t.Errorf("el espectador ve los dados de %s: %v", seat.ID, seat.Dice)
+		}
+		if seat.DiceCount != 5 {
+			t.Errorf("%s deberia mostrar 5 dados, muestra %d", seat.ID, seat.DiceCount)
+		}
+	}
+
+	for _, id := range []string{"ana", "beto"} {
+		view, err := room.PlayerView(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(view.MyDice, real[id]) {
+			t.Errorf("%s ve %v como propios, tiene %v", id, view.MyDice, real[id])
+		}
+		for _, seat := range view.Opponents() {
+			if seat.Dice != nil {
+				t.Errorf("%s ve los dados de %s: %v", id, seat.ID, seat.Dice)
+			}
+		}
+	}
+
+	// modificar la vista no toca la sala
+	view, _ := room.PlayerView("ana")
+	view.MyDice[0] = 0
+	if !slices.Equal(dice(room)["ana"], real["ana"]) {
+		t.Error("la vista comparte los dados con la sala")
+	}
+}
+
+func TestViewsRevealDiceWhenFinished(t *testing.T) {
+	room := newTestGame(t)
+	real := dice(room)
+
+	current := room.SpectatorView().CurrentPlayerID
+	if err := room.PlaceBet(current, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := room.CallLiar(room.SpectatorView().CurrentPlayerID); err != nil {
+		t.Fatal(err)
+	}
+
+	spectator := room.SpectatorView()
+	if spectator.Status != "FINISHED" || spectator.Result == nil {
+		t.Fatalf("la sala deberia estar FINISHED con resultado: %s", spectator.Status)
+	}
+	for _, seat := range spectator.Seats {
+		if !slices.Equal(seat.Dice, real[seat.ID]) {
+			t.Errorf("revelacion de %s = %v, tiene %v", seat.ID, seat.Dice, real[seat.ID])
+		}
+	}
+
+	view, err := room.PlayerView("beto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, seat := range view.Opponents() {
+		if !slices.Equal(seat.Dice, real[seat.ID]) {
+			t.Errorf("Beto deberia ver los dados de %s: %v", seat.ID, seat.Dice)
+		}
+	}
+}
+
+func TestPlayerViewRequiresSeat(t *testing.T) {
+	room := newTestGame(t)
+	if _, err := room.PlayerView("carla"); err != ErrNotAdmitted {
+		t.Errorf("PlayerView de alguien sin asiento = %v, se esperaba ErrNotAdmitted", err)
+	}
+}
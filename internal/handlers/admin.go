@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/olahol/melody"
+)
+
+// adminCookie guarda la prueba de que el navegador conoce el token de admin
+const adminCookie = "admin"
+
+// AdminHandler es el panel de operadores en /admin: una consola HTML y una API JSON
+// (/admin/api) que tambien usa cmd/admin. Todo pide el admin_token de la configuracion,
+// como "Authorization: Bearer <token>" o con la cookie que deja el login de la consola.
+// Ve y modifica las salas de esta instancia.
+type AdminHandler struct {
+	Manager *game.GameManager
+	GameH   *GameHandler
+	WSH     *WSHandler
+}
+
+func NewAdminHandler(gm *game.GameManager, gh *GameHandler, wsh *WSHandler) *AdminHandler {
+	return &AdminHandler{
+		Manager: gm,
+		GameH:   gh,
+		WSH:     wsh,
+	}
+}
+
+// Routes arma el subrouter que se monta en /admin. Sin admin_token no existe (404).
+func (h *AdminHandler) Routes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(h.enabled)
+
+	r.Get("/login", h.LoginPage)
+	r.With(h.GameH.LimitJoin).Post("/login", h.Login)
+	r.Post("/logout", h.Logout)
+
+	// Consola
+	r.Group(func(r chi.Router) {
+		r.Use(h.requireAdmin)
+		r.Get("/", h.Console)
+		r.Get("/rooms/{roomID}", h.RoomPage)
+	})
+
+	// API JSON
+	r.Route("/api", func(r chi.Router) {
+		r.Use(h.requireAdmin)
+		r.Get("/rooms", h.ListRooms)
+		r.Get("/rooms/{roomID}", h.GetRoom)
+		r.Post("/rooms/{roomID}/end", h.EndRoom)
+		r.Delete("/rooms/{roomID}", h.DeleteRoom)
+		r.Delete("/rooms/{roomID}/players/{playerID}", h.KickPlayer)
+		r.Get("/announcement", h.GetAnnouncement)
+		r.Put("/announcement", h.SetAnnouncement)
+		r.Delete("/announcement", h.ClearAnnouncement)
+		r.Get("/diagnostics", h.Diagnostics)
+	})
+	return r
+}
+
+func (h *AdminHandler) enabled(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.GameH.Config.AdminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminProof es el valor de la cookie: un HMAC del token, asi el token no viaja en cada pedido
+func (h *AdminHandler) adminProof() string {
+	mac := hmac.New(sha256.New, []byte(h.GameH.Config.AdminToken))
+	mac.Write([]byte("admin-console"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// isAdmin indica si el pedido trae el token (Bearer) o la cookie de la consola
+func (h *AdminHandler) isAdmin(r *http.Request) bool {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token := strings.TrimPrefix(auth, "Bearer ")
+		return subtle.ConstantTimeCompare([]byte(token), []byte(h.GameH.Config.AdminToken)) == 1
+	}
+	if cookie, err := r.Cookie(adminCookie); err == nil {
+		return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(h.adminProof())) == 1
+	}
+	return false
+}
+
+// requireAdmin corta los pedidos sin credenciales: la API responde 401, la consola
+// manda al login
+func (h *AdminHandler) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.isAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/admin/api/") {
+			writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, i18n.T(locale(r), "error.unauthorized"))
+			return
+		}
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+	})
+}
+
+// LoginPage muestra el formulario del token
+func (h *AdminHandler) LoginPage(w http.ResponseWriter, r *http.Request) {
+	h.GameH.render(w, r, "admin_login.html", map[string]interface{}{
+		"Error": r.URL.Query().Get("error") != "",
+	})
+}
+
+// Login valida el token y deja la cookie de la consola
+func (h *AdminHandler) Login(w http.ResponseWriter, r *http.Request) {
+	token := r.PostFormValue("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.GameH.Config.AdminToken)) != 1 {
+		requestLogger(r).Warn("login de admin fallido", "ip", clientIP(r))
+		http.Redirect(w, r, "/admin/login?error=1", http.StatusSeeOther)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookie,
+		Value:    h.adminProof(),
+		Path:     "/admin",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	requestLogger(r).Info("login de admin", "ip", clientIP(r))
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
+
+// Logout borra la cookie de la consola
+func (h *AdminHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     adminCookie,
+		Path:     "/admin",
+		HttpOnly: true,
+		MaxAge:   -1,
+	})
+	http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+}
+
+// Console lista las salas de la instancia y el anuncio vigente
+func (h *AdminHandler) Console(w http.ResponseWriter, r *http.Request) {
+	h.GameH.render(w, r, "admin.html", map[string]interface{}{
+		"Rooms":    h.roomList(),
+		"Message":  h.GameH.Announcement(),
+		"Instance": h.Manager.Instance(),
+	})
+}
+
+// RoomPage muestra el estado completo de una sala, dados incluidos.
+// Ojo: la clave no puede ser "RoomID", base.html abriria el WebSocket de la sala.
+func (h *AdminHandler) RoomPage(w http.ResponseWriter, r *http.Request) {
+	room, err := h.Manager.GetRoom(chi.URLParam(r, "roomID"))
+	if err != nil {
+		http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+		return
+	}
+	h.GameH.render(w, r, "admin_room.html", map[string]interface{}{
+		"Room": newAdminRoom(room.Info(), h.WSH.hub.count(room.ID)),
+	})
+}
+
+// adminPlayer es un jugador visto desde el panel (siempre con sus dados)
+type adminPlayer struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsHost    bool   `json:"is_host"`
+	Ready     bool   `json:"ready"`
+	IsTurn    bool   `json:"is_turn"`
+	DiceCount int    `json:"dice_count"`
+	Dice      []int  `json:"dice"`
+}
+
+// adminRoom es una sala vista desde el panel
+type adminRoom struct {
+	RoomID       string        `json:"room_id"`
+	Status       string        `json:"status"`
+	Private      bool          `json:"private"`
+	HasPassword  bool          `json:"has_password"`
+	Config       configBody    `json:"config"`
+	Players      []adminPlayer `json:"players"`
+	Connections  int           `json:"connections"`
+	CreatedAt    time.Time     `json:"created_at"`
+	AgeSeconds   int           `json:"age_seconds"`
+	CurrentBet   *betView      `json:"current_bet,omitempty"`
+	TurnDeadline *time.Time    `json:"turn_deadline,omitempty"`
+	LastResult   *resultView   `json:"last_result,omitempty"`
+	Kicked       []string      `json:"kicked,omitempty"`
+}
+
+// Age es la edad de la sala para la consola (por ejemplo "12m30s")
+func (a adminRoom) Age() string {
+	return (time.Duration(a.AgeSeconds) * time.Second).String()
+}
+
+func newAdminRoom(info game.RoomInfo, connections int) adminRoom {
+	room := adminRoom{
+		RoomID:      info.RoomID,
+		Status:      info.Status,
+		Private:     info.Private,
+		HasPassword: info.HasPassword,
+		Config:      newConfigBody(info.Config),
+		Players:     make([]adminPlayer, 0, len(info.Seats)),
+		Connections: connections,
+		CreatedAt:   info.CreatedAt,
+		AgeSeconds:  int(time.Since(info.CreatedAt).Seconds()),
+		Kicked:      info.Kicked,
+	}
+	for _, seat := range info.Seats {
+		room.Players = append(room.Players, adminPlayer{
+			ID:        seat.ID,
+			Name:      seat.Name,
+			IsHost:    seat.IsHost,
+			Ready:     seat.Ready,
+			IsTurn:    seat.IsTurn,
+			DiceCount: seat.DiceCount,
+			Dice:      diceToInts(seat.Dice),
+		})
+	}
+	if info.CurrentBetQuantity > 0 {
+		room.CurrentBet = &betView{Quantity: info.CurrentBetQuantity, Face: info.CurrentBetFace, PlayerID: info.LastBetPlayerID}
+	}
+	if !info.TurnDeadline.IsZero() {
+		deadline := info.TurnDeadline
+		room.TurnDeadline = &deadline
+	}
+	if res := info.Result; res != nil {
+		room.LastResult = &resultView{
+			AccuserID:   res.AccuserID,
+			BlufferID:   res.BlufferID,
+			BetQuantity: res.BetQuantity,
+			BetFace:     res.BetFace,
+			RealCount:   res.RealCount,
+			IsLiar:      res.IsLiar,
+			WinnerID:    res.WinnerID,
+			LoserID:     res.LoserID,
+		}
+	}
+	return room
+}
+
+// roomList devuelve las salas de la instancia, las mas nuevas primero
+func (h *AdminHandler) roomList() []adminRoom {
+	rooms := make([]adminRoom, 0)
+	for _, room := range h.Manager.Rooms() {
+		rooms = append(rooms, newAdminRoom(room.Info(), h.WSH.hub.count(room.ID)))
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return rooms[i].CreatedAt.After(rooms[j].CreatedAt)
+	})
+	return rooms
+}
+
+// adminDone responde una accion del panel. A la consola (htmx) se le pide recargar la
+// pagina, o ir a redirect si no esta vacio; a la API se le devuelve v.
+func adminDone(w http.ResponseWriter, r *http.Request, redirect string, v any) {
+	if r.Header.Get("HX-Request") == "true" {
+		if redirect != "" {
+			w.Header().Set("HX-Redirect", redirect)
+		} else {
+			w.Header().Set("HX-Refresh", "true")
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if v == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// adminRoomParam busca la sala de la URL entre las de esta instancia
+func (h *AdminHandler) adminRoomParam(w http.ResponseWriter, r *http.Request) (*game.Room, bool) {
+	room, err := h.Manager.GetRoom(chi.URLParam(r, "roomID"))
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return nil, false
+	}
+	return room, true
+}
+
+// ListRooms devuelve todas las salas de la instancia (publicas y privadas)
+func (h *AdminHandler) ListRooms(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"instance": h.Manager.Instance(),
+		"rooms":    h.roomList(),
+	})
+}
+
+// GetRoom devuelve el estado completo de una sala
+func (h *AdminHandler) GetRoom(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.adminRoomParam(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, newAdminRoom(room.Info(), h.WSH.hub.count(room.ID)))
+}
+
+// EndRoom corta la partida en curso y devuelve la sala al lobby
+func (h *AdminHandler) EndRoom(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.adminRoomParam(w, r)
+	if !ok {
+		return
+	}
+	if err := room.ForceEnd(); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	requestLogger(r).Info("admin: partida terminada", "room_id", room.ID)
+	adminDone(w, r, "", newAdminRoom(room.Info(), h.WSH.hub.count(room.ID)))
+}
+
+// DeleteRoom cierra la sala: se avisa a los conectados y se cortan sus WebSockets
+func (h *AdminHandler) DeleteRoom(w http.ResponseWriter, r *http.Request) {
+	roomID := chi.URLParam(r, "roomID")
+	if err := h.Manager.DeleteRoom(roomID); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.WSH.closeSessions(roomID, "", codeRoomDeleted)
+	requestLogger(r).Info("admin: sala eliminada", "room_id", roomID)
+	adminDone(w, r, "/admin/", nil)
+}
+
+// KickPlayer saca a un jugador de la sala, corta su WebSocket y no lo deja volver
+func (h *AdminHandler) KickPlayer(w http.ResponseWriter, r *http.Request) {
+	room, ok := h.adminRoomParam(w, r)
+	if !ok {
+		return
+	}
+	playerID := chi.URLParam(r, "playerID")
+	if err := room.Kick(playerID); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.WSH.closeSessions(room.ID, playerID, codeKicked)
+	requestLogger(r).Info("admin: jugador expulsado", "room_id", room.ID, "player_id", playerID)
+	adminDone(w, r, "", nil)
+}
+
+// announcementBody es el anuncio vigente ("" = ninguno)
+type announcementBody struct {
+	Message string `json:"message"`
+}
+
+// maxAnnouncementLength limita el largo del anuncio (en bytes)
+const maxAnnouncementLength = 280
+
+// GetAnnouncement devuelve el anuncio vigente
+func (h *AdminHandler) GetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, announcementBody{Message: h.GameH.Announcement()})
+}
+
+// SetAnnouncement publica un anuncio en todas las salas. La consola lo manda como
+// formulario, la API como JSON.
+func (h *AdminHandler) SetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var body announcementBody
+	if isJSONRequest(r) {
+		if !decodeBody(w, r, &body) {
+			return
+		}
+	} else {
+		body.Message = r.FormValue("message")
+	}
+	body.Message = strings.TrimSpace(body.Message)
+	if len(body.Message) > maxAnnouncementLength {
+		writeAPIError(w, http.StatusBadRequest, codeInvalidAnnouncement, i18n.T(locale(r), "error.invalid_announcement", maxAnnouncementLength))
+		return
+	}
+
+	h.GameH.setAnnouncement(body.Message)
+	h.WSH.BroadcastAnnouncement(body.Message)
+	requestLogger(r).Info("admin: anuncio", "message", body.Message)
+	adminDone(w, r, "", body)
+}
+
+// ClearAnnouncement saca el anuncio de todas las pantallas
+func (h *AdminHandler) ClearAnnouncement(w http.ResponseWriter, r *http.Request) {
+	h.GameH.setAnnouncement("")
+	h.WSH.BroadcastAnnouncement("")
+	requestLogger(r).Info("admin: anuncio borrado")
+	adminDone(w, r, "", nil)
+}
+
+// Announcement devuelve el anuncio vigente (las paginas lo muestran al cargar)
+func (h *GameHandler) Announcement() string {
+	h.announcementMutex.RLock()
+	defer h.announcementMutex.RUnlock()
+	return h.announcement
+}
+
+func (h *GameHandler) setAnnouncement(message string) {
+	h.announcementMutex.Lock()
+	defer h.announcementMutex.Unlock()
+	h.announcement = message
+}
+
+// BroadcastAnnouncement muestra (o saca, con message vacio) el banner en todas las salas.
+// El anuncio se muestra tal como lo escribio el operador, en cualquier idioma.
+func (h *WSHandler) BroadcastAnnouncement(message string) {
+	banner, err := h.GameH.renderFragment(i18n.Default, "announcement_banner", message)
+	if err != nil {
+		slog.Error("error renderizando template", "template", "announcement_banner", "err", err)
+		return
+	}
+	h.Melody.BroadcastFilter([]byte(banner), func(s *melody.Session) bool {
+		return !isJSONSession(s)
+	})
+	h.Melody.BroadcastFilter(encodeServerMessage("event", "", map[string]string{"event": "announcement", "message": message}), isJSONSession)
+}
+
+// RoomLost cierra las conexiones de una sala que paso a otra instancia; al reconectar
+// los jugadores llegan a traves del mirror
+func (h *WSHandler) RoomLost(roomID string) {
+	h.closeSessions(roomID, "", codeRoomMoved)
+}
+
+// RoomClosed cierra las conexiones que quedaban en una sala abandonada
+func (h *WSHandler) RoomClosed(roomID string) {
+	h.closeSessions(roomID, "", game.ErrRoomClosed.Code)
+}
+
+// closeSessions avisa el motivo (code, traducido para cada uno) y cierra las conexiones
+// de la sala (o solo las de playerID si no esta vacio)
+func (h *WSHandler) closeSessions(roomID, playerID, code string) {
+	for _, s := range h.hub.sessions(roomID) {
+		if playerID != "" && s.MustGet("playerID").(string) != playerID {
+			continue
+		}
+		lang := sessionLang(s)
+		message := i18n.T(lang, "error."+code)
+		if isJSONSession(s) {
+			s.Write(encodeServerMessage("error", "", apiError{Code: code, Message: message}))
+		} else {
+			s.Write([]byte(h.errorBannerHTML(lang, message)))
+		}
+		s.Set("closedBy", code) // el cierre no cuenta como que el jugador se fue
+		s.Close()
+	}
+}
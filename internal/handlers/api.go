@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// APIHandler expone el juego como JSON en /api/v1 para clientes que no son el navegador.
+// Usa las mismas llamadas de internal/game que los handlers HTMX; los WebSockets se
+// enteran de cada cambio por las actualizaciones de la sala (ver WSHandler.watch).
+type APIHandler struct {
+	Manager *game.GameManager
+	GameH   *GameHandler
+}
+
+func NewAPIHandler(gm *game.GameManager, gh *GameHandler) *APIHandler {
+	return &APIHandler{
+		Manager: gm,
+		GameH:   gh,
+	}
+}
+
+// Routes arma el subrouter que se monta en /api/v1
+func (h *APIHandler) Routes() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/rooms", h.ListRooms)
+	r.With(h.GameH.LimitCreate).Post("/rooms", h.CreateRoom)
+	r.With(h.GameH.LimitJoin).Post("/rooms/{roomID}/join", h.JoinRoom)
+	r.Get("/rooms/{roomID}", h.GetRoom)
+	r.Delete("/rooms/{roomID}/players/me", h.LeaveRoom)
+
+	// Acciones de juego
+	r.Group(func(r chi.Router) {
+		r.Use(h.GameH.LimitActions)
+		r.Put("/rooms/{roomID}/config", h.UpdateConfig)
+		r.Post("/rooms/{roomID}/start", h.StartGame)
+		r.Post("/rooms/{roomID}/bets", h.PlaceBet)
+		r.Post("/rooms/{roomID}/liar", h.CallLiar)
+		r.Post("/rooms/{roomID}/next-round", h.NextRound)
+		r.Post("/rooms/{roomID}/reset", h.ResetRoom)
+	})
+	return r
+}
+
+// apiError es el cuerpo de todas las respuestas de error
+type apiError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"` // datos del error del juego, como la apuesta minima
+}
+
+// writeJSON serializa la respuesta con el status indicado
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError responde con {"error": {...}}
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]apiError{"error": {Code: code, Message: message}})
+}
+
+// decodeBody lee el JSON del cuerpo; un cuerpo vacio deja los valores por defecto
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, codeInvalidBody, i18n.T(locale(r), "error.invalid_body", err.Error()))
+		return false
+	}
+	return true
+}
+
+// apiRoom resuelve el jugador y la sala de la URL, respondiendo el error si falta alguno
+func (h *APIHandler) apiRoom(w http.ResponseWriter, r *http.Request) (*game.Room, string, bool) {
+	sess, ok := h.GameH.currentSession(r)
+	if !ok {
+		writeAPIError(w, http.StatusUnauthorized, codeUnauthorized, i18n.T(locale(r), "error.unauthorized"))
+		return nil, "", false
+	}
+	playerID := sess.PlayerID
+	room, err := h.Manager.GetRoom(chi.URLParam(r, "roomID"))
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return nil, "", false
+	}
+	return room, playerID, true
+}
+
+// sessionResponse es lo que recibe el cliente al crear o unirse a una sala
+type sessionResponse struct {
+	RoomID   string `json:"room_id"`
+	PlayerID string `json:"player_id"`
+	Token    string `json:"token"`
+}
+
+// configBody es la configuracion de sala en JSON
+type configBody struct {
+	DicesAmount     int  `json:"dices_amount"`
+	MaxPlayers      int  `json:"max_players"`
+	TurnDuration    int  `json:"turn_duration"`
+	MinBetIncrement int  `json:"min_bet_increment"`
+	WildAces        bool `json:"wild_aces"`
+}
+
+func newConfigBody(c game.GameConfig) configBody {
+	return configBody{
+		DicesAmount:     c.DicesAmount,
+		MaxPlayers:      c.MaxPlayers,
+		TurnDuration:    c.TurnDuration,
+		MinBetIncrement: c.MinBetIncrement,
+		WildAces:        c.WildAces,
+	}
+}
+
+func (c configBody) gameConfig() game.GameConfig {
+	return game.GameConfig{
+		DicesAmount:     c.DicesAmount,
+		MaxPlayers:      c.MaxPlayers,
+		TurnDuration:    c.TurnDuration,
+		MinBetIncrement: c.MinBetIncrement,
+		WildAces:        c.WildAces,
+	}
+}
+
+// ListRooms devuelve las salas publicas esperando jugadores
+func (h *APIHandler) ListRooms(w http.ResponseWriter, r *http.Request) {
+	type roomItem struct {
+		RoomID      string     `json:"room_id"`
+		PlayerCount int        `json:"player_count"`
+		Config      configBody `json:"config"`
+	}
+	items := make([]roomItem, 0)
+	for _, s := range h.Manager.PublicRooms() {
+		items = append(items, roomItem{RoomID: s.ID, PlayerCount: s.PlayerCount, Config: newConfigBody(s.Config)})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rooms": items})
+}
+
+// CreateRoom crea una sala y sienta al creador como host
+func (h *APIHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		PlayerName string      `json:"player_name"`
+		Private    bool        `json:"private"`
+		Password   string      `json:"password"`
+		Config     *configBody `json:"config"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	name, err := game.NormalizePlayerName(body.PlayerName)
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	body.PlayerName = name
+
+	room, err := h.Manager.CreateRoom(h.GameH.newRoomCode(), h.GameH.Config.GameConfig())
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	room.SetAccess(body.Private, body.Password)
+
+	// si no se puede sentar al creador la sala se borra, sino queda huerfana
+	playerID := uuid.New().String()
+	err = room.Admit(playerID, body.Password)
+	if err == nil {
+		err = room.AddPlayer(&game.Player{ID: playerID, Name: body.PlayerName})
+	}
+	if err == nil && body.Config != nil {
+		err = room.UpdateConfig(playerID, body.Config.gameConfig())
+	}
+	if err != nil {
+		h.Manager.DeleteRoom(room.ID)
+		h.GameH.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sessionResponse{
+		RoomID:   room.ID,
+		PlayerID: playerID,
+		Token:    h.GameH.Sessions.Issue(playerID, body.PlayerName),
+	})
+}
+
+// JoinRoom sienta a un jugador nuevo en una sala existente
+func (h *APIHandler) JoinRoom(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		PlayerName string `json:"player_name"`
+		Password   string `json:"password"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	name, err := game.NormalizePlayerName(body.PlayerName)
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	body.PlayerName = name
+
+	room, err := h.Manager.GetRoom(chi.URLParam(r, "roomID"))
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+
+	playerID := uuid.New().String()
+	if err := room.Admit(playerID, body.Password); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	if err := room.AddPlayer(&game.Player{ID: playerID, Name: body.PlayerName}); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, sessionResponse{
+		RoomID:   room.ID,
+		PlayerID: playerID,
+		Token:    h.GameH.Sessions.Issue(playerID, body.PlayerName),
+	})
+}
+
+// LeaveRoom saca al jugador de la sala
+func (h *APIHandler) LeaveRoom(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	room.RemovePlayer(playerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetRoom devuelve la sala tal como la ve el jugador (sin los dados ajenos mientras se juega)
+func (h *APIHandler) GetRoom(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	view, err := newRoomView(room, playerID)
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// UpdateConfig cambia la configuracion de la sala (solo host)
+func (h *APIHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	var body configBody
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if err := room.UpdateConfig(playerID, body.gameConfig()); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.respondView(w, r, room, playerID)
+}
+
+// StartGame arranca la partida (solo host)
+func (h *APIHandler) StartGame(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	if err := room.StartGame(playerID); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.respondView(w, r, room, playerID)
+}
+
+// PlaceBet hace una apuesta en el turno del jugador
+func (h *APIHandler) PlaceBet(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Quantity int `json:"quantity"`
+		Face     int `json:"face"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if err := room.PlaceBet(playerID, body.Quantity, body.Face); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.respondView(w, r, room, playerID)
+}
+
+// CallLiar desafia la ultima apuesta
+func (h *APIHandler) CallLiar(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	if _, err := room.CallLiar(playerID); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.respondView(w, r, room, playerID)
+}
+
+// NextRound arranca otra ronda con la misma configuracion (solo host)
+func (h *APIHandler) NextRound(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	if err := room.NextRound(playerID); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.respondView(w, r, room, playerID)
+}
+
+// ResetRoom vuelve la sala al lobby (solo host)
+func (h *APIHandler) ResetRoom(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := h.apiRoom(w, r)
+	if !ok {
+		return
+	}
+	if err := room.Reset(playerID); err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	h.respondView(w, r, room, playerID)
+}
+
+// respondView contesta una accion con el estado actualizado de la sala
+func (h *APIHandler) respondView(w http.ResponseWriter, r *http.Request, room *game.Room, playerID string) {
+	view, err := newRoomView(room, playerID)
+	if err != nil {
+		h.GameH.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// roomView es el estado de la sala que ve un jugador
+type roomView struct {
+	RoomID          string       `json:"room_id"`
+	Status          string       `json:"status"`
+	Config          configBody   `json:"config"`
+	Players         []playerView `json:"players"`
+	MyID            string       `json:"my_id"`
+	MyDice          []int        `json:"my_dice"`
+	CurrentPlayerID string       `json:"current_player_id,omitempty"`
+	CurrentBet      *betView     `json:"current_bet,omitempty"`
+	TurnDeadline    *time.Time   `json:"turn_deadline,omitempty"` // hora exacta en que vence el turno
+	SecondsLeft     int          `json:"seconds_left"`
+	LastResult      *resultView  `json:"last_result,omitempty"`
+}
+
+type playerView struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	IsHost    bool   `json:"is_host"`
+	Ready     bool   `json:"ready"`
+	DiceCount int    `json:"dice_count"`
+	Dice      []int  `json:"dice,omitempty"` // solo en la pantalla de resultados
+}
+
+type betView struct {
+	Quantity int    `json:"quantity"`
+	Face     int    `json:"face"`
+	PlayerID string `json:"player_id"`
+}
+
+type resultView struct {
+	AccuserID   string `json:"accuser_id"`
+	BlufferID   string `json:"bluffer_id"`
+	BetQuantity int    `json:"bet_quantity"`
+	BetFace     int    `json:"bet_face"`
+	RealCount   int    `json:"real_count"`
+	IsLiar      bool   `json:"is_liar"`
+	WinnerID    string `json:"winner_id"`
+	LoserID     string `json:"loser_id"`
+}
+
+// newRoomView traduce a JSON la vista del jugador que arma internal/game
+// (ahi se decide que dados puede ver cada uno)
+func newRoomView(room *game.Room, playerID string) (roomView, error) {
+	pv, err := room.PlayerView(playerID)
+	if err != nil {
+		return roomView{}, err
+	}
+
+	view := roomView{
+		RoomID:      pv.RoomID,
+		Status:      pv.Status,
+		Config:      newConfigBody(pv.Config),
+		Players:     make([]playerView, 0, len(pv.Seats)),
+		MyID:        pv.MyID,
+		MyDice:      diceToInts(pv.MyDice),
+		SecondsLeft: pv.SecondsLeft,
+	}
+	if !pv.TurnDeadline.IsZero() {
+		deadline := pv.TurnDeadline
+		view.TurnDeadline = &deadline
+	}
+
+	for _, seat := range pv.Seats {
+		p := playerView{ID: seat.ID, Name: seat.Name, IsHost: seat.IsHost, Ready: seat.Ready, DiceCount: seat.DiceCount}
+		if seat.Dice != nil {
+			p.Dice = diceToInts(seat.Dice)
+		}
+		view.Players = append(view.Players, p)
+	}
+
+	view.CurrentPlayerID = pv.CurrentPlayerID
+	if pv.CurrentBetQuantity > 0 {
+		view.CurrentBet = &betView{
+			Quantity: pv.CurrentBetQuantity,
+			Face:     pv.CurrentBetFace,
+			PlayerID: pv.LastBetPlayerID,
+		}
+	}
+
+	if res := pv.Result; res != nil {
+		view.LastResult = &resultView{
+			AccuserID:   res.AccuserID,
+			BlufferID:   res.BlufferID,
+			BetQuantity: res.BetQuantity,
+			BetFace:     res.BetFace,
+			RealCount:   res.RealCount,
+			IsLiar:      res.IsLiar,
+			WinnerID:    res.WinnerID,
+			LoserID:     res.LoserID,
+		}
+	}
+	return view, nil
+}
+
+func diceToInts(dice []game.Dice) []int {
+	out := make([]int, len(dice))
+	for i, d := range dice {
+		out[i] = int(d)
+	}
+	return out
+}
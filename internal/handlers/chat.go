@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"log/slog"
+	"time"
+
+	"github.com/olahol/melody"
+)
+
+// El chat es parte del estado de la sala (ver game/chat.go): los mensajes viajan como
+// acciones y llegan a todos con las actualizaciones de la sala, aunque esten conectados
+// a otra instancia. El navegador recibe fragmentos de #chat-log; los clientes JSON,
+// mensajes "chat" y el evento "chat_history".
+
+// sendChat publica el mensaje del jugador respetando su limite de mensajes
+func (h *WSHandler) sendChat(room *game.Room, playerID, text string) error {
+	if !h.GameH.chatLimit.Allow(playerID) {
+		return game.ErrChatRateLimited
+	}
+	return room.SendChat(playerID, text)
+}
+
+// chatLogHTML arma el historial del chat (o solo los mensajes nuevos si appendOnly)
+// tal como lo ve la sesion: el host tiene los botones para silenciar y borrar
+func (h *WSHandler) chatLogHTML(lang string, messages []game.ChatMessage, view game.SpectatorView, hostID string, appendOnly bool) (string, error) {
+	muted := make(map[string]bool)
+	for _, seat := range view.Seats {
+		if seat.Muted {
+			muted[seat.ID] = true
+		}
+	}
+	return h.GameH.renderFragment(lang, "chat_log", map[string]interface{}{
+		"Messages": messages,
+		"Append":   appendOnly,
+		"HostID":   hostID, // vacio si la sesion no es del host
+		"Muted":    muted,
+	})
+}
+
+// writeChatHistory manda el historial completo a una sesion (al entrar o al resincronizar)
+func (h *WSHandler) writeChatHistory(s *melody.Session, room *game.Room, playerID string) {
+	messages := room.Chat()
+	if isJSONSession(s) {
+		s.Write(encodeServerMessage("event", "", map[string]any{"event": "chat_history", "messages": messages}))
+		return
+	}
+	hostID := ""
+	if room.IsHost(playerID) {
+		hostID = playerID
+	}
+	out, err := h.chatLogHTML(sessionLang(s), messages, room.SpectatorView(), hostID, false)
+	if err != nil {
+		slog.Error("error renderizando template", "template", "chat_log", "room_id", room.ID, "err", err)
+		return
+	}
+	s.Write([]byte(out))
+}
+
+// broadcastChat manda a toda la sala los mensajes nuevos del chat.
+// Con full se reemplaza el historial entero (se borro, alguien fue silenciado o cambio el host).
+func (h *WSHandler) broadcastChat(room *game.Room, messages []game.ChatMessage, full bool) {
+	defer broadcastLatency.Since(time.Now(), "chat")
+
+	if full {
+		messages = room.Chat()
+	}
+	view := room.SpectatorView()
+	hostID := ""
+	for _, seat := range view.Seats {
+		if seat.IsHost {
+			hostID = seat.ID
+		}
+	}
+
+	// se arma una vez por idioma y por rol (host o no)
+	rendered := make(map[string]string)
+	for _, s := range h.hub.sessions(room.ID) {
+		if isJSONSession(s) {
+			if full {
+				s.Write(encodeServerMessage("event", "", map[string]any{"event": "chat_history", "messages": messages}))
+				continue
+			}
+			for _, msg := range messages {
+				s.Write(encodeServerMessage("chat", "", msg))
+			}
+			continue
+		}
+
+		lang := sessionLang(s)
+		sessionHost := ""
+		if s.MustGet("playerID").(string) == hostID {
+			sessionHost = hostID
+		}
+		key := lang + "|" + sessionHost
+		out, ok := rendered[key]
+		if !ok {
+			var err error
+			out, err = h.chatLogHTML(lang, messages, view, sessionHost, !full)
+			if err != nil {
+				slog.Error("error renderizando template", "template", "chat_log", "room_id", room.ID, "err", err)
+				return
+			}
+			rendered[key] = out
+		}
+		s.Write([]byte(out))
+	}
+}
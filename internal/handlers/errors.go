@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+// categoryStatus es el status HTTP de cada categoria de error del juego
+var categoryStatus = map[game.Category]int{
+	game.CategoryInvalid:     http.StatusBadRequest,
+	game.CategoryForbidden:   http.StatusForbidden,
+	game.CategoryConflict:    http.StatusConflict,
+	game.CategoryNotFound:    http.StatusNotFound,
+	game.CategoryUnavailable: http.StatusServiceUnavailable,
+}
+
+// Codigos de error propios de los handlers: no son errores del juego, los arma el
+// servidor (limites, protocolo, cierres). Junto con game.ErrorCodes() son todos los
+// "code" que puede recibir un cliente y cada uno tiene su error.<codigo> en los catalogos.
+const (
+	codeInternal            = "internal"
+	codeUnauthorized        = "unauthorized"
+	codeInvalidBody         = "invalid_body"
+	codeInvalidAnnouncement = "invalid_announcement"
+	codeRateLimited         = "rate_limited"
+	codeInvalidMessage      = "invalid_message"
+	codeUnsupportedVersion  = "unsupported_version"
+	codeUnknownType         = "unknown_type"
+	codeRoomDeleted         = "room_deleted"
+	codeRoomMoved           = "room_moved"
+	codeKicked              = "kicked"
+)
+
+// handlerCodes son los codigos de arriba, para recorrerlos (ver errors_test.go)
+var handlerCodes = []string{
+	codeInternal, codeUnauthorized, codeInvalidBody, codeInvalidAnnouncement, codeRateLimited,
+	codeInvalidMessage, codeUnsupportedVersion, codeUnknownType, codeRoomDeleted, codeRoomMoved, codeKicked,
+}
+
+// errInternal es como ve el cliente un error que no es del juego
+var errInternal = &game.Error{Code: codeInternal}
+
+// clientError es un error tal como lo ve el cliente
+type clientError struct {
+	Status int      // status HTTP
+	Body   apiError // cuerpo de la API y del protocolo JSON del WebSocket
+	Target string   // elemento propio de la accion donde htmx muestra el mensaje, ademas del aviso general
+}
+
+// translateError es el unico lugar que decide como se le muestra un error al cliente:
+// el status sale de la categoria, el mensaje del catalogo del idioma (error.<codigo>)
+// y el lugar en pantalla de la accion que fallo
+func translateError(lang, action string, err error) clientError {
+	var e *game.Error
+	if !errors.As(err, &e) {
+		slog.Error("error inesperado", "err", err)
+		e = errInternal
+	}
+	status, ok := categoryStatus[e.Category]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	return clientError{
+		Status: status,
+		Body:   apiError{Code: e.Code, Message: i18n.T(lang, "error."+e.Code), Details: e.Details},
+		Target: actionTarget(action),
+	}
+}
+
+// actionTarget es el elemento donde se muestran los errores de cada accion, ademas
+// del aviso general: los de la apuesta van debajo de los controles y los del chat
+// debajo del campo de texto
+func actionTarget(action string) string {
+	switch action {
+	case "bet":
+		return "bet-error"
+	case "chat":
+		return "chat-error"
+	}
+	return ""
+}
+
+// writeError responde el error del juego. A htmx le llega el aviso de #action-feedback
+// (se ubica solo con hx-swap-oob; base.html hace que se procese aunque el status sea de
+// error); al resto, el JSON de la API.
+func (h *GameHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	lang := locale(r)
+	ce := translateError(lang, "", err)
+	if r.Header.Get("HX-Request") == "true" {
+		out, renderErr := h.renderFragment(lang, "action_feedback", map[string]interface{}{
+			"Message": ce.Body.Message,
+			"Target":  ce.Target,
+		})
+		if renderErr == nil {
+			w.Header().Set("HX-Retarget", "#action-feedback")
+			w.Header().Set("HX-Reswap", "none")
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(ce.Status)
+			w.Write([]byte(out))
+			return
+		}
+		slog.Error("error renderizando template", "template", "action_feedback", "err", renderErr)
+	}
+	writeJSON(w, ce.Status, map[string]apiError{"error": ce.Body})
+}
+
+// redirectHome vuelve a la pantalla principal mostrando el error (los formularios sin htmx)
+func redirectHome(w http.ResponseWriter, r *http.Request, err error) {
+	ce := translateError(locale(r), "", err)
+	http.Redirect(w, r, "/?error="+ce.Body.Code, http.StatusSeeOther)
+}
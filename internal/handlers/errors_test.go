@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Cada codigo que puede recibir un cliente (los errores del juego y los propios de los
+// handlers) tiene que tener su error.<codigo> en todos los catalogos. Se leen los
+// archivos directo porque i18n.T cae al catalogo por defecto y taparia la clave faltante.
+func TestErrorCodesTranslated(t *testing.T) {
+	codes := append(game.ErrorCodes(), handlerCodes...)
+
+	for _, lang := range i18n.Languages {
+		data, err := os.ReadFile(filepath.Join("..", "i18n", "locales", lang.Code+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			t.Fatalf("catalogo %s: %v", lang.Code, err)
+		}
+		for _, code := range codes {
+			if catalog["error."+code] == "" {
+				t.Errorf("al catalogo %s le falta error.%s", lang.Code, code)
+			}
+		}
+	}
+}
+
+// Los errores de los limites del chat y de las reacciones los arman los handlers pero
+// se registran en el juego, asi entran en game.ErrorCodes() y LookupError los encuentra
+func TestRateLimitErrorsRegistered(t *testing.T) {
+	for _, e := range []*game.Error{game.ErrChatRateLimited, game.ErrReactionRateLimited} {
+		if got, ok := game.LookupError(e.Code); !ok || got != e {
+			t.Errorf("%s no esta registrado en el juego", e.Code)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/buildinfo"
+	"dados-mentirosos/internal/game"
+	"io"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// startedAt es la hora en que arranco el proceso (para el uptime del diagnostico)
+var startedAt = time.Now()
+
+// Healthz responde 200 mientras el proceso este vivo (liveness probe)
+func (h *GameHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "ok\n")
+}
+
+// Readyz indica si la instancia puede recibir trafico (readiness probe): templates
+// cargados, store accesible y sin estar apagandose. Si algo falla responde 503 con el detalle.
+func (h *GameHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{
+		"templates": "ok",
+		"store":     "ok",
+		"draining":  "ok",
+	}
+	ready := true
+	if err := h.Templates.Check(); err != nil {
+		checks["templates"] = err.Error()
+		ready = false
+	}
+	if err := h.Manager.CheckStore(); err != nil {
+		checks["store"] = err.Error()
+		ready = false
+	}
+	if h.Manager.IsDraining() {
+		checks["draining"] = "el servidor se esta apagando"
+		ready = false
+	}
+
+	status, state := http.StatusOK, "ok"
+	if !ready {
+		status, state = http.StatusServiceUnavailable, "unavailable"
+	}
+	writeJSON(w, status, map[string]any{"status": state, "checks": checks})
+}
+
+// diagnostics es el estado interno de la instancia que devuelve /admin/api/diagnostics
+type diagnostics struct {
+	Build           buildinfo.Info `json:"build"`
+	Instance        string         `json:"instance,omitempty"`
+	UptimeSeconds   int            `json:"uptime_seconds"`
+	Goroutines      int            `json:"goroutines"`
+	Rooms           int            `json:"rooms"`
+	ArmedTurnTimers int            `json:"armed_turn_timers"`
+	WSSessions      int            `json:"ws_sessions"`             // todas las que tiene abiertas melody
+	WSByProtocol    map[string]int `json:"ws_sessions_by_protocol"` // las que ya estan en una sala
+	Draining        bool           `json:"draining"`
+}
+
+// Diagnostics devuelve goroutines, timers, sesiones y la version del binario (solo admin)
+func (h *AdminHandler) Diagnostics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, diagnostics{
+		Build:           buildinfo.Get(),
+		Instance:        h.Manager.Instance(),
+		UptimeSeconds:   int(time.Since(startedAt).Seconds()),
+		Goroutines:      runtime.NumGoroutine(),
+		Rooms:           len(h.Manager.Rooms()),
+		ArmedTurnTimers: game.ArmedTurnTimers(),
+		WSSessions:      h.WSH.Melody.Len(),
+		WSByProtocol:    h.WSH.hub.protocols(),
+		Draining:        h.Manager.IsDraining(),
+	})
+}
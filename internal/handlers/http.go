@@ -2,30 +2,140 @@ package handlers
 
 import (
 	"math/rand"
+	"dados-mentirosos/internal/config"
 	"dados-mentirosos/internal/game"
-	"fmt"
-	"html/template"
+	"dados-mentirosos/internal/i18n"
+	"dados-mentirosos/internal/ratelimit"
+	"dados-mentirosos/internal/session"
+	"log/slog"
+	"io"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// sessionCookie es la cookie donde el navegador guarda su token de sesion
+const sessionCookie = "session"
+
 type GameHandler struct {
 	Manager   *game.GameManager
+	Config    config.Config
+	Matchmaker *game.Matchmaker
+	Sessions  *session.Signer
+	Templates *Templates
+
+	// limites de pedidos (ver ratelimit.go)
+	createLimit *rateLimit
+	joinLimit   *rateLimit
+	actionLimit *rateLimit
+	chatLimit   *ratelimit.Limiter // mensajes de chat por jugador, en todas sus conexiones
+	reactLimit  *ratelimit.Limiter // reacciones por jugador
+	trustedProxies []*net.IPNet    // proxies de los que se acepta X-Forwarded-For (ver RealIP)
+
+	// anuncio del panel de admin que se muestra en todas las pantallas (ver admin.go)
+	announcementMutex sync.RWMutex
+	announcement      string
 }
 
-func NewGameHandler(manager *game.GameManager) *GameHandler {
-	return &GameHandler{
+func NewGameHandler(manager *game.GameManager, cfg config.Config, tmpls *Templates) *GameHandler {
+	secret := []byte(cfg.SessionSecret)
+	if len(secret) == 0 {
+		slog.Warn("sin session_secret: las sesiones se invalidan al reiniciar el servidor")
+		secret = session.RandomSecret()
+	}
+
+	h := &GameHandler{
 		Manager:   manager,
+		Config:    cfg,
+		Sessions:  session.NewSigner(secret, cfg.SessionTTL),
+		Templates: tmpls,
+	}
+	h.newRateLimits()
+	h.trustedProxies = cfg.TrustedProxyNets()
+	h.Matchmaker = game.NewMatchmaker(manager, cfg.GameConfig(), h.newRoomCode, cfg.MatchMinPlayers, cfg.MatchMaxWait)
+	return h
+}
+
+// newRoomCode genera un codigo de sala con el largo configurado
+func (h *GameHandler) newRoomCode() string {
+	return generateRoomCode(h.Config.RoomCodeLength)
+}
+
+// setSession emite el token firmado del jugador y lo guarda en la cookie de sesion
+// (que vence junto con el token)
+func (h *GameHandler) setSession(w http.ResponseWriter, r *http.Request, playerID, playerName string) string {
+	token := h.Sessions.Issue(playerID, playerName)
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(h.Sessions.TTL().Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// currentSession resuelve quien hace el pedido. Es el unico lugar donde se lee la identidad:
+// "Authorization: Bearer <token>" (API) o la cookie (navegador). Al abrir el WebSocket
+// tambien vale ?token= (ver wsSession).
+func (h *GameHandler) currentSession(r *http.Request) (session.Session, bool) {
+	token := ""
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	} else if cookie, err := r.Cookie(sessionCookie); err == nil {
+		token = cookie.Value
+	}
+	return h.verifySession(token)
+}
+
+// wsSession es currentSession mas ?token=, que los clientes JSON usan porque no siempre
+// pueden mandar encabezados al abrir el WebSocket. Solo se acepta ahi: en cualquier otro
+// pedido el token en la URL terminaria en logs e historiales.
+func (h *GameHandler) wsSession(r *http.Request) (session.Session, bool) {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return h.verifySession(token)
+	}
+	return h.currentSession(r)
+}
+
+// verifySession valida el token (firma y vencimiento)
+func (h *GameHandler) verifySession(token string) (session.Session, bool) {
+	if token == "" {
+		return session.Session{}, false
+	}
+	sess, err := h.Sessions.Verify(token)
+	if err != nil {
+		return session.Session{}, false
 	}
+	return sess, true
 }
 
 // Home sirve la pantalla principal
 func (h *GameHandler) Home(w http.ResponseWriter, r *http.Request) {
-	h.render(w, "home.html", nil)
+	data := map[string]interface{}{
+		"PublicRooms": h.Manager.PublicRooms(),
+	}
+	// los formularios vuelven aca con el codigo del error (ver redirectHome)
+	if err, ok := game.LookupError(r.URL.Query().Get("error")); ok {
+		data["Error"] = translateError(locale(r), "", err).Body.Message
+	}
+	h.render(w, r, "home.html", data)
+}
+
+// PublicRooms devuelve solo el listado de salas publicas (lo refresca HTMX)
+func (h *GameHandler) PublicRooms(w http.ResponseWriter, r *http.Request) {
+	data := map[string]interface{}{
+		"PublicRooms": h.Manager.PublicRooms(),
+		"CSRFToken":   csrfToken(r),
+	}
+	h.writeFragment(w, r, "public_rooms", data)
 }
 
 // generateRoomCode para crear un codigo alfanumerico de n caracteres
@@ -41,70 +151,72 @@ func generateRoomCode(length int) string {
 	return string(b)
 }
 
-// render carga el layout, la pagina solicitada Y los partials necesarios explicitamente
-func (h *GameHandler) render(w http.ResponseWriter, page string, data any) {
-	files := []string{
-		"ui/html/base.html",       // El esqueleto
-		"ui/html/pages/" + page,   // La pagina (home.html o lobby.html)
-	}
+// render ejecuta la pagina completa (base + pagina + partials) desde los templates ya parseados
+func (h *GameHandler) render(w http.ResponseWriter, r *http.Request, page string, data map[string]interface{}) {
+	// Todas las paginas llevan el token CSRF (hx-headers del body y formularios)
+	data["CSRFToken"] = csrfToken(r)
+	data["Announcement"] = h.Announcement()
+	// el selector de idioma vuelve a esta misma pagina
+	data["Path"] = r.URL.RequestURI()
 
-	if page == "lobby.html" {
-		files = append(files, "ui/html/partials/lobby/settings.html")
-		files = append(files, "ui/html/partials/lobby/controls.html")
+	lang := locale(r)
+	err := h.Templates.ExecutePage(w, lang, page, "base", data)
+	if err != nil {
+		requestLogger(r).Error("error renderizando template", "template", page, "err", err)
+		http.Error(w, i18n.T(lang, "error.render", err.Error()), http.StatusInternalServerError)
 	}
+}
 
-	tmpl := template.New("base")
-	
-	// Funciones auxiliares
-	tmpl.Funcs(template.FuncMap{
-		"toInt": func(i any) int { return 0 }, 
-	})
+// renderFragment ejecuta un partial en el idioma pedido y devuelve el HTML como string.
+// Los fragmentos que viajan por WebSocket se arman siempre asi, para que html/template
+// escape lo que escriben los jugadores.
+func (h *GameHandler) renderFragment(lang, name string, data any) (string, error) {
+	return h.Templates.Fragment(lang, name, data)
+}
 
-	// Parsear la lista exacta de archivos
-	var err error
-	tmpl, err = tmpl.ParseFiles(files...)
+// writeFragment responde un partial suelto a un pedido de HTMX
+func (h *GameHandler) writeFragment(w http.ResponseWriter, r *http.Request, name string, data any) {
+	lang := locale(r)
+	out, err := h.renderFragment(lang, name, data)
 	if err != nil {
-		// Logueamos el error en la terminal para que sepas que archivo falta
-		fmt.Println("❌ Error ParseFiles:", err) 
-		http.Error(w, "Error cargando archivos: "+err.Error(), http.StatusInternalServerError)
+		requestLogger(r).Error("error renderizando template", "template", name, "err", err)
+		http.Error(w, i18n.T(lang, "error.render", err.Error()), http.StatusInternalServerError)
 		return
 	}
-
-	// Ejecutar
-	err = tmpl.ExecuteTemplate(w, "base", data)
-	if err != nil {
-		fmt.Println("❌ Error ExecuteTemplate:", err)
-		http.Error(w, "Error renderizando: "+err.Error(), http.StatusInternalServerError)
-	}
+	io.WriteString(w, out)
 }
 
 // CreateRoom procesa el formulario y redirige
 func (h *GameHandler) CreateRoom(w http.ResponseWriter, r *http.Request) {
 	// Se leen datos del formulario
 	r.ParseForm()
-	playerName := r.FormValue("player_name")
-	
-	// Por ahora usamos la default para probar el flujo
-	config := game.GameConfig{
-		MaxPlayers: 7,
-		DicesAmount: 5,
-		MinBetIncrement: 1,
-		WildAces: false,
+	playerName, err := game.NormalizePlayerName(r.FormValue("player_name"))
+	if err != nil {
+		redirectHome(w, r, err)
+		return
 	}
+	
+	// La sala arranca con los valores por defecto de la configuracion del servidor
+	config := h.Config.GameConfig()
 
-	// Se genera ID unico para la sala de 5 caracteres
-	roomID := generateRoomCode(5)
+	// Se genera ID unico para la sala
+	roomID := h.newRoomCode()
+
+	// Se crea la sala en memoria (falla si el servidor se esta apagando o hay demasiadas salas)
+	room, err := h.Manager.CreateRoom(roomID, config)
+	if err != nil {
+		redirectHome(w, r, err)
+		return
+	}
 
-	// Se crea la sala en memoria
-	h.Manager.CreateRoom(roomID, config)
+	// Privada = no aparece en el listado, con contraseña opcional
+	isPrivate := r.FormValue("visibility") == "private"
+	room.SetAccess(isPrivate, r.FormValue("password"))
 
 	// Se crea cookie de secion para saber quien es este usuario (simplificado)
 	playerID := uuid.New().String()
-	http.SetCookie(w, &http.Cookie{
-		Name:  "player_id",
-		Value: playerID + ":" + playerName, // Hack sucio para guardar nombre, idealmente en servidor
-		Path:  "/",
-	})
+	room.Admit(playerID, r.FormValue("password"))
+	h.setSession(w, r, playerID, playerName)
 
 	// Se redirige a la sala
 	http.Redirect(w, r, "/room/"+roomID, http.StatusSeeOther)
@@ -116,30 +228,28 @@ func (h *GameHandler) Room(w http.ResponseWriter, r *http.Request) {
 
 	room, err := h.Manager.GetRoom(roomID)
 	if err != nil {
-		http.Redirect(w, r, "/?error=notfound", http.StatusSeeOther)
+		redirectHome(w, r, err)
 		return
 	}
+	setRoomOwner(w, h.Manager, roomID)
 
-	// Detectar host leyendo la cookie
+	// Detectar host leyendo la sesion
+	view := room.SpectatorView()
 	isHost := false
-	cookie, err := r.Cookie("player_id")
-	if err == nil {
-		parts := strings.Split(cookie.Value, ":")
-		playerID := parts[0]
-
-		if p, ok := room.Players[playerID]; ok && p.IsHost {
+	if sess, ok := h.currentSession(r); ok {
+		if room.IsHost(sess.PlayerID) {
 			isHost = true
-		} else if len(room.Players) == 0 {
+		} else if len(view.Seats) == 0 {
 			// Caso especial: Si la sala esta vacia, el primero que entra sera el host
 			isHost = true
 		}
 	}
 	data := map[string]interface{}{
-		"RoomID": room.ID,
-		"Config": room.Config,
+		"RoomID": view.RoomID,
+		"Config": view.Config,
 		"IsHost": isHost,
 	}
-	h.render(w, "lobby.html", data)
+	h.render(w, r, "lobby.html", data)
 }
 
 // JoinRoom procesa la solicitud de unirse a una sala existente
@@ -147,40 +257,53 @@ func (h *GameHandler) JoinRoom(w http.ResponseWriter, r *http.Request) {
 	// Parsear datos
 	err := r.ParseForm()
 	if err != nil {
-		http.Error(w, "Error en formulario", http.StatusBadRequest)
+		http.Error(w, i18n.T(locale(r), "error.bad_form"), http.StatusBadRequest)
 		return
 	}
 
-	playerName := r.FormValue("player_name")
+	playerName, err := game.NormalizePlayerName(r.FormValue("player_name"))
+	if err != nil {
+		redirectHome(w, r, err)
+		return
+	}
 	roomID := strings.ToLower(strings.TrimSpace(r.FormValue("room_id")))
 
 	// Validar que la sala exista Y que se pueda entrar
 	room, err := h.Manager.GetRoom(roomID)
 	if err != nil {
 		// Si no existe volvemos al home con error
-		http.Redirect(w, r, "/?error=notfound", http.StatusSeeOther)
+		redirectHome(w, r, err)
 		return
 	}
 
 	// Validar que la sala no este llena
-	if len(room.Players) >= room.Config.MaxPlayers {
-		http.Redirect(w, r, "/?error=full", http.StatusSeeOther)
+	view := room.SpectatorView()
+	if len(view.Seats) >= view.Config.MaxPlayers {
+		redirectHome(w, r, game.ErrRoomFull)
 		return
 	}
 
 	// Validar que la partida no haya empezado
-	if room.Status != "WAITING" {
-		http.Redirect(w, r, "/?error=started", http.StatusSeeOther)
+	if view.Status != "WAITING" {
+		redirectHome(w, r, game.ErrGameStarted)
+		return
+	}
+
+	// Validar que nadie de la sala use el mismo nombre
+	if room.NameTaken(playerName) {
+		redirectHome(w, r, game.ErrNameTaken)
 		return
 	}
 
 	// Crear Cookie de Sesión
 	playerID := uuid.New().String()
-	http.SetCookie(w, &http.Cookie{
-		Name:  "player_id",
-		Value: playerID + ":" + playerName,
-		Path:  "/",
-	})
+
+	// Validar la contraseña si la sala la pide
+	if err := room.Admit(playerID, r.FormValue("password")); err != nil {
+		redirectHome(w, r, err)
+		return
+	}
+	h.setSession(w, r, playerID, playerName)
 
 	// Redirigir al Lobby
 	http.Redirect(w, r, "/room/"+roomID, http.StatusSeeOther)
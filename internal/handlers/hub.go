@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"errors"
+	"sync"
+
+	"github.com/olahol/melody"
+)
+
+// sessionHub indexa las sesiones WebSocket por sala, asi un broadcast recorre solo
+// las conexiones de esa sala y no todas las del servidor.
+type sessionHub struct {
+	mutex sync.RWMutex
+	rooms map[string]map[*melody.Session]bool
+}
+
+func newSessionHub() *sessionHub {
+	return &sessionHub{rooms: make(map[string]map[*melody.Session]bool)}
+}
+
+// add registra la sesion en su sala
+func (hub *sessionHub) add(roomID string, s *melody.Session) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	sessions, ok := hub.rooms[roomID]
+	if !ok {
+		sessions = make(map[*melody.Session]bool)
+		hub.rooms[roomID] = sessions
+	}
+	sessions[s] = true
+}
+
+// remove saca la sesion de su sala (y la sala del indice si queda vacia)
+func (hub *sessionHub) remove(roomID string, s *melody.Session) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	sessions, ok := hub.rooms[roomID]
+	if !ok {
+		return
+	}
+	delete(sessions, s)
+	if len(sessions) == 0 {
+		delete(hub.rooms, roomID)
+	}
+}
+
+// sessions devuelve una copia de las sesiones de la sala
+func (hub *sessionHub) sessions(roomID string) []*melody.Session {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	list := make([]*melody.Session, 0, len(hub.rooms[roomID]))
+	for s := range hub.rooms[roomID] {
+		list = append(list, s)
+	}
+	return list
+}
+
+// playerSessions devuelve cuantas conexiones tiene abiertas el jugador en la sala
+func (hub *sessionHub) playerSessions(roomID, playerID string) int {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	n := 0
+	for s := range hub.rooms[roomID] {
+		if id, ok := s.Get("playerID"); ok && id.(string) == playerID {
+			n++
+		}
+	}
+	return n
+}
+
+// count devuelve cuantas sesiones hay conectadas a la sala
+func (hub *sessionHub) count(roomID string) int {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+	return len(hub.rooms[roomID])
+}
+
+// protocols cuenta las sesiones conectadas a alguna sala, por protocolo
+func (hub *sessionHub) protocols() map[string]int {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	counts := map[string]int{"html": 0, "json": 0}
+	for _, sessions := range hub.rooms {
+		for s := range sessions {
+			if isJSONSession(s) {
+				counts["json"]++
+			} else {
+				counts["html"]++
+			}
+		}
+	}
+	return counts
+}
+
+// Un cliente lento nunca frena a la sala: melody descarta lo que no entra en el buffer
+// de la sesion y la marcamos como desactualizada ("stale"). En el proximo broadcast
+// se le manda el estado completo en vez de un fragmento parcial.
+
+// handleError marca las sesiones que perdieron mensajes
+func (h *WSHandler) handleError(s *melody.Session, err error) {
+	if !errors.Is(err, melody.ErrMessageBufferFull) {
+		return
+	}
+	if stale, _ := s.Get("stale"); stale == true {
+		return // ya estaba marcada, se avisa una vez por racha
+	}
+	s.Set("stale", true)
+	sessionLogger(s).Warn("cliente lento, se descartan mensajes hasta resincronizar")
+}
+
+// resync manda el estado completo y el historial del chat a una sesion marcada como
+// desactualizada. Devuelve false si la sesion estaba al dia.
+func (h *WSHandler) resync(s *melody.Session, room *game.Room, playerID string) bool {
+	if stale, _ := s.Get("stale"); stale != true {
+		return false
+	}
+	s.Set("stale", false)
+	h.writeState(s, room, playerID)
+	h.writeChatHistory(s, room, playerID)
+	return true
+}
+
+// writeState manda la pantalla completa (o el snapshot JSON) a una sesion
+func (h *WSHandler) writeState(s *melody.Session, room *game.Room, playerID string) {
+	if isJSONSession(s) {
+		h.writeJSONState(s, room, playerID)
+		return
+	}
+	s.Write([]byte(h.generateStateHTML(room, playerID, sessionLang(s))))
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/i18n"
+	"net/http"
+	"strings"
+
+	"github.com/olahol/melody"
+)
+
+// langCookie guarda el idioma que eligio el jugador; le gana a Accept-Language
+const langCookie = "lang"
+
+// locale resuelve el idioma del pedido: ?lang= (clientes de la API y del WebSocket JSON),
+// la cookie que deja el selector y por ultimo Accept-Language
+func locale(r *http.Request) string {
+	if lang := i18n.Normalize(r.URL.Query().Get("lang")); lang != "" {
+		return lang
+	}
+	if cookie, err := r.Cookie(langCookie); err == nil {
+		if lang := i18n.Normalize(cookie.Value); lang != "" {
+			return lang
+		}
+	}
+	return i18n.Match(r.Header.Get("Accept-Language"))
+}
+
+// sessionLang es el idioma de una conexion WebSocket (se resuelve al abrirla)
+func sessionLang(s *melody.Session) string {
+	if lang, ok := s.Get("lang"); ok {
+		return lang.(string)
+	}
+	return i18n.Default
+}
+
+// SetLanguage guarda el idioma elegido en el selector y vuelve a la pagina donde estaba
+func (h *GameHandler) SetLanguage(w http.ResponseWriter, r *http.Request) {
+	if lang := i18n.Normalize(r.FormValue("lang")); lang != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     langCookie,
+			Value:    lang,
+			Path:     "/",
+			MaxAge:   365 * 24 * 60 * 60,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	// solo rutas locales, para no servir de redireccion abierta
+	next := r.FormValue("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		next = "/"
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/olahol/melody"
+)
+
+// quietPaths son rutas que se consultan periodicamente: se loguean solo en debug
+var quietPaths = map[string]bool{
+	"/metrics":            true,
+	"/healthz":            true,
+	"/readyz":             true,
+	"/rooms":              true,
+	"/quick-match/status": true,
+}
+
+// RequestLogger loguea cada pedido HTTP con su request_id (lo pone middleware.RequestID).
+// Reemplaza al logger de texto de chi para que todo salga por slog.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			ww.Header().Set(middleware.RequestIDHeader, id)
+		}
+
+		next.ServeHTTP(ww, r)
+
+		level := slog.LevelInfo
+		switch {
+		case ww.Status() >= 500:
+			level = slog.LevelError
+		case quietPaths[r.URL.Path]:
+			level = slog.LevelDebug
+		}
+		requestLogger(r).Log(r.Context(), level, "pedido http",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", ww.Status(),
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+			"ip", clientIP(r),
+		)
+	})
+}
+
+// requestLogger devuelve el logger con el request_id del pedido
+func requestLogger(r *http.Request) *slog.Logger {
+	if id := middleware.GetReqID(r.Context()); id != "" {
+		return slog.With("request_id", id)
+	}
+	return slog.Default()
+}
+
+// sessionLogger devuelve el logger de una conexion WebSocket: sala, jugador y el
+// request_id del pedido que abrio el socket
+func sessionLogger(s *melody.Session) *slog.Logger {
+	args := make([]any, 0, 6)
+	if id, ok := s.Get("requestID"); ok && id.(string) != "" {
+		args = append(args, "request_id", id)
+	}
+	if roomID, ok := s.Get("roomID"); ok {
+		args = append(args, "room_id", roomID)
+	}
+	if playerID, ok := s.Get("playerID"); ok {
+		args = append(args, "player_id", playerID)
+	}
+	return slog.With(args...)
+}
+
+// logAction deja registro de una accion de juego y su resultado
+func logAction(logger *slog.Logger, action string, err error) {
+	if err != nil {
+		logger.Info("accion rechazada", "action", action, "status", "error", "err", err)
+		return
+	}
+	logger.Info("accion", "action", action, "status", "ok")
+}
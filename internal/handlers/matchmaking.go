@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// QuickMatch pone al jugador en la cola de partida rapida y lo manda a la pantalla de espera
+func (h *GameHandler) QuickMatch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, i18n.T(locale(r), "error.bad_form"), http.StatusBadRequest)
+		return
+	}
+	playerName, err := game.NormalizePlayerName(r.FormValue("player_name"))
+	if err != nil {
+		redirectHome(w, r, err)
+		return
+	}
+
+	// Preferencias opcionales, vacio = "me da igual"
+	prefs := game.AnyPrefs()
+	if dices := atoi(r.FormValue("dices_amount")); dices >= 1 && dices <= 6 {
+		prefs.DicesAmount = dices
+	}
+	if v := r.FormValue("turn_duration"); v != "" {
+		prefs.TurnDuration = atoi(v) // el matchmaker lo deja entre 0 y game.MaxTurnDuration
+	}
+	if v := r.FormValue("wild_aces"); v == "on" || v == "off" {
+		prefs.WildAces = v
+	}
+
+	// la cola se arma por jugador: con sesion se reusa su ID, asi no puede entrar dos veces
+	playerID := uuid.New().String()
+	if sess, ok := h.currentSession(r); ok {
+		playerID = sess.PlayerID
+	}
+	if err := h.Matchmaker.Enqueue(playerID, playerName, prefs); err != nil {
+		redirectHome(w, r, err)
+		return
+	}
+
+	h.setSession(w, r, playerID, playerName)
+	http.Redirect(w, r, "/quick-match", http.StatusSeeOther)
+}
+
+// QuickMatchWait sirve la pantalla de espera
+func (h *GameHandler) QuickMatchWait(w http.ResponseWriter, r *http.Request) {
+	data := h.queueStatus(r)
+	if roomID, ok := data["RoomID"].(string); ok && roomID != "" {
+		http.Redirect(w, r, "/room/"+roomID, http.StatusSeeOther)
+		return
+	}
+	if data["Queued"] == false {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	h.render(w, r, "matchmaking.html", data)
+}
+
+// QuickMatchStatus lo consulta la pantalla de espera cada pocos segundos.
+// Cuando ya hay sala se redirige al jugador con HX-Redirect.
+func (h *GameHandler) QuickMatchStatus(w http.ResponseWriter, r *http.Request) {
+	data := h.queueStatus(r)
+	if roomID, ok := data["RoomID"].(string); ok && roomID != "" {
+		w.Header().Set("HX-Redirect", "/room/"+roomID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if data["Queued"] == false {
+		w.Header().Set("HX-Redirect", "/")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	h.writeFragment(w, r, "queue_status", data)
+}
+
+// QuickMatchCancel saca al jugador de la cola y lo devuelve al inicio
+func (h *GameHandler) QuickMatchCancel(w http.ResponseWriter, r *http.Request) {
+	if sess, ok := h.currentSession(r); ok {
+		h.Matchmaker.Cancel(sess.PlayerID)
+	}
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
+
+// queueStatus arma los datos de la pantalla de espera para el jugador de la sesion
+func (h *GameHandler) queueStatus(r *http.Request) map[string]interface{} {
+	data := map[string]interface{}{"Queued": false}
+
+	sess, ok := h.currentSession(r)
+	if !ok {
+		return data
+	}
+
+	ticket, queueLen, ok := h.Matchmaker.Status(sess.PlayerID)
+	if !ok {
+		return data
+	}
+	if ticket.RoomID != "" {
+		data["RoomID"] = ticket.RoomID
+		return data
+	}
+
+	data["Queued"] = true
+	data["QueueLen"] = queueLen
+	data["Waiting"] = int(time.Since(ticket.QueuedAt).Seconds())
+	data["MinPlayers"] = h.Matchmaker.MinPlayers
+	data["MaxWait"] = int(h.Matchmaker.MaxWait.Seconds())
+	return data
+}
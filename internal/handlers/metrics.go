@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/buildinfo"
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/metrics"
+	"strings"
+
+	"github.com/olahol/melody"
+)
+
+// Metricas de la capa web (las del juego estan en internal/game/metrics.go)
+var (
+	templateRender   = metrics.NewHistogram("dados_template_render_seconds", "Duracion de la ejecucion de templates, por pagina o fragmento.", nil, "template")
+	wsMessagesSent   = metrics.NewCounter("dados_ws_messages_sent_total", "Mensajes enviados por WebSocket, por protocolo (html o json).", "protocol")
+	broadcastLatency = metrics.NewHistogram("dados_broadcast_seconds", "Tiempo en armar y encolar un broadcast a toda una sala, por tipo (state, players, chat, reaction o timer).", nil, "kind")
+)
+
+// RegisterMetrics registra los gauges que se calculan en cada scrape de /metrics
+func RegisterMetrics(gm *game.GameManager, m *melody.Melody) {
+	metrics.NewGaugeFunc("dados_rooms", "Salas activas en esta instancia, por estado.", []string{"status"}, func() []metrics.Sample {
+		counts := map[string]int{"WAITING": 0, "PLAYING": 0, "FINISHED": 0}
+		for _, room := range gm.Rooms() {
+			counts[room.SpectatorView().Status]++
+		}
+		samples := make([]metrics.Sample, 0, len(counts))
+		for status, n := range counts {
+			samples = append(samples, metrics.Sample{Labels: []string{strings.ToLower(status)}, Value: float64(n)})
+		}
+		return samples
+	})
+
+	// /metrics es publico: las salas privadas no muestran su codigo, se suman todas en
+	// room="private"
+	metrics.NewGaugeFunc("dados_room_players", "Jugadores sentados en cada sala publica de esta instancia (las privadas juntas en room=\"private\").", []string{"room"}, func() []metrics.Sample {
+		counts := make(map[string]int)
+		for _, room := range gm.Rooms() {
+			view := room.SpectatorView()
+			label := view.RoomID
+			if view.Private {
+				label = "private"
+			}
+			counts[label] += len(view.Seats)
+		}
+		samples := make([]metrics.Sample, 0, len(counts))
+		for room, n := range counts {
+			samples = append(samples, metrics.Sample{Labels: []string{room}, Value: float64(n)})
+		}
+		return samples
+	})
+
+	metrics.NewGaugeFunc("dados_players", "Jugadores sentados en las salas de esta instancia, por estado de la sala.", []string{"status"}, func() []metrics.Sample {
+		counts := map[string]int{"WAITING": 0, "PLAYING": 0, "FINISHED": 0}
+		for _, room := range gm.Rooms() {
+			view := room.SpectatorView()
+			counts[view.Status] += len(view.Seats)
+		}
+		samples := make([]metrics.Sample, 0, len(counts))
+		for status, n := range counts {
+			samples = append(samples, metrics.Sample{Labels: []string{strings.ToLower(status)}, Value: float64(n)})
+		}
+		return samples
+	})
+
+	metrics.NewGaugeFunc("dados_ws_sessions", "Conexiones WebSocket abiertas en esta instancia.", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(m.Len())}}
+	})
+
+	metrics.NewGaugeFunc("dados_turn_timers_armed", "Timers de turno corriendo en esta instancia.", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(game.ArmedTurnTimers())}}
+	})
+
+	build := buildinfo.Get()
+	metrics.NewGaugeFunc("dados_build_info", "Version del binario (siempre 1).", []string{"version", "commit", "go_version"}, func() []metrics.Sample {
+		return []metrics.Sample{{Labels: []string{build.Version, build.Commit, build.GoVersion}, Value: 1}}
+	})
+}
+
+// countSent cuenta cada mensaje que melody termina de mandar
+func countSent(s *melody.Session, _ []byte) {
+	protocol := "html"
+	if isJSONSession(s) {
+		protocol = "json"
+	}
+	wsMessagesSent.Inc(protocol)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/i18n"
+	"dados-mentirosos/internal/ratelimit"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/olahol/melody"
+)
+
+// ipFactor es cuanto mas holgado es el limite por IP que el de cada sesion
+// (detras de una misma IP puede haber varios jugadores)
+const ipFactor = 4
+
+// rateLimit junta los limites de un tipo de pedido: por IP y por sesion
+type rateLimit struct {
+	kind       string
+	ip         *ratelimit.Limiter
+	player     *ratelimit.Limiter
+	retryAfter int // segundos hasta que vuelve a haber una ficha
+}
+
+// newRateLimit crea un limite de perSecond pedidos por segundo con rafagas de burst
+func newRateLimit(kind string, perSecond float64, burst int) *rateLimit {
+	retry := 1
+	if perSecond > 0 {
+		retry = int(math.Ceil(1 / perSecond))
+	}
+	return &rateLimit{
+		kind:       kind,
+		ip:         ratelimit.New(perSecond*ipFactor, burst*ipFactor),
+		player:     ratelimit.New(perSecond, burst),
+		retryAfter: retry,
+	}
+}
+
+// allow gasta una ficha de la IP y, si hay sesion, otra del jugador
+func (l *rateLimit) allow(ip, playerID string) bool {
+	if !l.ip.Allow(ip) {
+		return false
+	}
+	return playerID == "" || l.player.Allow(playerID)
+}
+
+// newRateLimits arma los limites a partir de la configuracion
+func (h *GameHandler) newRateLimits() {
+	h.createLimit = newRateLimit("crear sala", float64(h.Config.RateCreate)/60, h.Config.RateCreate)
+	h.joinLimit = newRateLimit("unirse", float64(h.Config.RateJoin)/60, h.Config.RateJoin)
+	h.actionLimit = newRateLimit("accion", float64(h.Config.RateActions), 2*h.Config.RateActions)
+	h.chatLimit = ratelimit.New(float64(h.Config.ChatRate)/60, max(1, h.Config.ChatRate/4))
+	h.reactLimit = ratelimit.New(float64(h.Config.ReactionRate)/60, max(1, h.Config.ReactionRate/6))
+}
+
+// LimitCreate limita la creacion de salas y las busquedas de partida rapida
+func (h *GameHandler) LimitCreate(next http.Handler) http.Handler {
+	return h.rateLimitMiddleware(h.createLimit, next)
+}
+
+// LimitJoin limita los ingresos a salas
+func (h *GameHandler) LimitJoin(next http.Handler) http.Handler {
+	return h.rateLimitMiddleware(h.joinLimit, next)
+}
+
+// LimitActions limita las acciones de juego de la API
+func (h *GameHandler) LimitActions(next http.Handler) http.Handler {
+	return h.rateLimitMiddleware(h.actionLimit, next)
+}
+
+// rateLimitMiddleware responde 429 cuando se pasa el limite.
+// La API recibe el error en JSON, el navegador un texto simple.
+func (h *GameHandler) rateLimitMiddleware(l *rateLimit, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		playerID := ""
+		if sess, ok := h.currentSession(r); ok {
+			playerID = sess.PlayerID
+		}
+
+		if l.allow(ip, playerID) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestLogger(r).Warn("limite de pedidos", "kind", l.kind, "ip", ip, "player_id", playerID, "method", r.Method, "path", r.URL.Path)
+		w.Header().Set("Retry-After", strconv.Itoa(l.retryAfter))
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			writeAPIError(w, http.StatusTooManyRequests, codeRateLimited, i18n.T(locale(r), "error.rate_limited"))
+			return
+		}
+		http.Error(w, i18n.T(locale(r), "error.rate_limited"), http.StatusTooManyRequests)
+	})
+}
+
+// clientIP devuelve la IP del que hace el pedido (sin puerto). Detras de un proxy de
+// confianza RealIP ya dejo en RemoteAddr la IP del cliente.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RealIP reemplaza RemoteAddr por la IP del cliente que informa X-Forwarded-For, solo si
+// el pedido llega desde un proxy de trusted_proxies (el encabezado lo puede inventar
+// cualquiera). Se recorre de derecha a izquierda salteando los proxies de confianza: la
+// primera IP ajena es la que agrego el ultimo proxy nuestro.
+func (h *GameHandler) RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip := h.forwardedFor(r); ip != "" {
+			r.RemoteAddr = net.JoinHostPort(ip, "0")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedFor devuelve la IP del cliente segun X-Forwarded-For ("" = usar RemoteAddr)
+func (h *GameHandler) forwardedFor(r *http.Request) string {
+	if len(h.trustedProxies) == 0 || !h.trusted(net.ParseIP(clientIP(r))) {
+		return ""
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break // encabezado roto: se queda con el ultimo salto valido
+		}
+		client = ip.String()
+		if !h.trusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+// trusted indica si la IP es de un proxy de confianza
+func (h *GameHandler) trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range h.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowMessage aplica a un mensaje del WebSocket el limite de la conexion y el de
+// acciones del jugador. El abuso se loguea una sola vez por racha.
+func (h *WSHandler) allowMessage(s *melody.Session) bool {
+	playerID := s.MustGet("playerID").(string)
+	ip := s.MustGet("ip").(string)
+
+	allowed := h.GameH.actionLimit.allow(ip, playerID)
+	if bucket, ok := s.Get("rate"); ok && allowed {
+		allowed = bucket.(*ratelimit.Bucket).Allow()
+	}
+
+	limited, _ := s.Get("limited")
+	if !allowed && limited != true {
+		sessionLogger(s).Warn("limite de mensajes WS", "ip", ip)
+	}
+	s.Set("limited", !allowed)
+	return allowed
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	cfg := config.Default()
+	cfg.RateCreate = 2
+	h := newTestHandler(t, cfg)
+	next := h.LimitCreate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	post := func(path, remote, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, nil)
+		req.RemoteAddr = remote
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// sin sesion solo cuenta la IP, que tiene ipFactor veces el limite de la sesion
+	for i := 0; i < 2*ipFactor; i++ {
+		if rec := post("/create-room", "192.0.2.1:1234", ""); rec.Code != http.StatusOK {
+			t.Fatalf("pedido %d desde la IP: status %d", i+1, rec.Code)
+		}
+	}
+	rec := post("/create-room", "192.0.2.1:1234", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("pasado el limite de la IP: status %d, se esperaba 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("Retry-After = %q, se esperaba 30 (2 por minuto)", rec.Header().Get("Retry-After"))
+	}
+
+	// con sesion, el jugador se corta antes aunque la IP tenga margen
+	token := h.Sessions.Issue("ana-id", "Ana")
+	for i := 0; i < 2; i++ {
+		if rec := post("/api/v1/rooms", "192.0.2.2:1234", token); rec.Code != http.StatusOK {
+			t.Fatalf("pedido %d de la sesion: status %d", i+1, rec.Code)
+		}
+	}
+	rec = post("/api/v1/rooms", "192.0.2.3:1234", token)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("pasado el limite de la sesion (desde otra IP): status %d, se esperaba 429", rec.Code)
+	}
+	var body map[string]apiError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body["error"].Code != codeRateLimited {
+		t.Errorf("la API deberia contestar JSON con %q: %s", codeRateLimited, rec.Body.String())
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	cfg := config.Default()
+	cfg.TrustedProxies = []string{"10.0.0.0/8"}
+	h := newTestHandler(t, cfg)
+	next := h.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(clientIP(r)))
+	}))
+
+	cases := []struct {
+		name, remote string
+		forwarded    []string
+		want         string
+	}{
+		{"sin proxy", "198.51.100.7:5000", nil, "198.51.100.7"},
+		{"encabezado de un cliente sin proxy", "198.51.100.7:5000", []string{"203.0.113.9"}, "198.51.100.7"},
+		{"un proxy de confianza", "10.0.0.2:5000", []string{"203.0.113.9"}, "203.0.113.9"},
+		{"dos proxies de confianza", "10.0.0.2:5000", []string{"203.0.113.9, 10.0.0.5"}, "203.0.113.9"},
+		{"IP inventada por el cliente", "10.0.0.2:5000", []string{"1.2.3.4, 203.0.113.9"}, "203.0.113.9"},
+		{"varios encabezados", "10.0.0.2:5000", []string{"1.2.3.4", "203.0.113.9"}, "203.0.113.9"},
+		{"salto roto", "10.0.0.2:5000", []string{"203.0.113.9, basura"}, "10.0.0.2"},
+		{"proxy sin encabezado", "10.0.0.2:5000", nil, "10.0.0.2"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remote
+		for _, v := range c.forwarded {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != c.want {
+			t.Errorf("%s: IP = %q, se esperaba %q", c.name, got, c.want)
+		}
+	}
+}
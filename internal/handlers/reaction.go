@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"log/slog"
+	"time"
+)
+
+// Las reacciones (ver game/reaction.go) son eventos livianos: no se redibuja la pantalla,
+// el navegador recibe un fragmento que se agrega sobre el asiento del jugador
+// (#reactions-<id>) y se desvanece solo; los clientes JSON, el evento "reaction".
+
+// sendReaction manda la reaccion del jugador respetando su limite
+func (h *WSHandler) sendReaction(room *game.Room, playerID, emoji string) error {
+	if !h.GameH.reactLimit.Allow(playerID) {
+		return game.ErrReactionRateLimited
+	}
+	return room.React(playerID, emoji)
+}
+
+// broadcastReactions manda las reacciones nuevas a toda la sala
+func (h *WSHandler) broadcastReactions(room *game.Room, reactions []game.Reaction) {
+	defer broadcastLatency.Since(time.Now(), "reaction")
+
+	// el fragmento no tiene textos, se arma una sola vez para todos
+	var html []byte
+	for _, s := range h.hub.sessions(room.ID) {
+		if isJSONSession(s) {
+			for _, reaction := range reactions {
+				s.Write(encodeServerMessage("event", "", map[string]any{"event": "reaction", "player_id": reaction.PlayerID, "emoji": reaction.Emoji}))
+			}
+			continue
+		}
+		if html == nil {
+			out, err := h.GameH.renderFragment(sessionLang(s), "reactions", reactions)
+			if err != nil {
+				slog.Error("error renderizando template", "template", "reactions", "room_id", room.ID, "err", err)
+				return
+			}
+			html = []byte(out)
+		}
+		s.Write(html)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"dados-mentirosos/internal/i18n"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// csrfCookie identifica al navegador antes de que tenga sesion (home, formularios)
+	csrfCookie = "csrf_id"
+	// csrfHeader lo manda htmx en cada pedido (hx-headers en base.html)
+	csrfHeader = "X-CSRF-Token"
+	// csrfField es el campo oculto de los formularios comunes
+	csrfField = "csrf_token"
+)
+
+type contextKey string
+
+const csrfTokenKey contextKey = "csrf_token"
+
+// SecurityHeaders agrega los headers de seguridad basicos a todas las respuestas.
+// La CSP no restringe scripts porque tailwind y htmx se cargan de CDNs.
+func SecurityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "same-origin")
+		header.Set("Cross-Origin-Opener-Policy", "same-origin")
+		header.Set("Permissions-Policy", "camera=(), microphone=(), geolocation=()")
+		header.Set("Content-Security-Policy", "frame-ancestors 'none'; base-uri 'self'; form-action 'self'; object-src 'none'")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfKey es lo que ata el token: el jugador de la sesion o, sin sesion, la cookie csrf_id
+func (h *GameHandler) csrfKey(r *http.Request) string {
+	if sess, ok := h.currentSession(r); ok {
+		return sess.PlayerID
+	}
+	if cookie, err := r.Cookie(csrfCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// CSRF exige el token en todo pedido que cambia estado (POST, PUT, DELETE, ...).
+// En los pedidos de lectura deja el token en el contexto para que los templates lo usen.
+// Los clientes de la API quedan afuera: mandan Authorization o JSON, cosas que un
+// formulario de otro sitio no puede hacer sin pasar por CORS.
+func (h *GameHandler) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := h.csrfKey(r)
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if key == "" {
+				key = uuid.New().String()
+				http.SetCookie(w, &http.Cookie{
+					Name:     csrfCookie,
+					Value:    key,
+					Path:     "/",
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			ctx := context.WithValue(r.Context(), csrfTokenKey, h.Sessions.CSRFToken(key))
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "" || isJSONRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.Header.Get(csrfHeader)
+		if token == "" {
+			token = r.PostFormValue(csrfField)
+		}
+		if !h.Sessions.CheckCSRF(key, token) {
+			requestLogger(r).Warn("token CSRF invalido", "method", r.Method, "path", r.URL.Path, "ip", clientIP(r))
+			http.Error(w, i18n.T(locale(r), "error.csrf"), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// csrfToken devuelve el token del pedido actual (lo deja el middleware CSRF)
+func csrfToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfTokenKey).(string)
+	return token
+}
+
+// isJSONRequest indica si el cuerpo se mando como application/json
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// checkOrigin decide si se acepta el upgrade a WebSocket. Sin header Origin es un
+// cliente que no es navegador; si no, tiene que ser el mismo host o uno configurado.
+func (h *WSHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range h.GameH.Config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	requestLogger(r).Warn("WebSocket rechazado: origen no permitido", "origin", origin)
+	return false
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/config"
+	"dados-mentirosos/internal/game"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestHandler arma un GameHandler sin templates, alcanza para probar los middlewares
+func newTestHandler(t *testing.T, cfg config.Config) *GameHandler {
+	t.Helper()
+	cfg.SessionSecret = "secreto-de-pruebas-0123456789"
+	h := NewGameHandler(game.NewGameManager(), cfg, nil)
+	t.Cleanup(h.Matchmaker.Stop)
+	return h
+}
+
+// csrfServer pasa por el middleware CSRF y contesta el token que dejo en el contexto
+func csrfServer(h *GameHandler) http.Handler {
+	return h.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(csrfToken(r)))
+	}))
+}
+
+func TestCSRFIssuesTokenOnGet(t *testing.T) {
+	h := newTestHandler(t, config.Default())
+
+	rec := httptest.NewRecorder()
+	csrfServer(h).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	var anon *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == csrfCookie {
+			anon = c
+		}
+	}
+	if anon == nil || !anon.HttpOnly {
+		t.Fatalf("se esperaba la cookie %s HttpOnly, llego %+v", csrfCookie, anon)
+	}
+	if got := rec.Body.String(); got == "" || got != h.Sessions.CSRFToken(anon.Value) {
+		t.Errorf("token del contexto = %q, no corresponde a la cookie", got)
+	}
+}
+
+func TestCSRFRejectsPostsWithoutValidToken(t *testing.T) {
+	h := newTestHandler(t, config.Default())
+	anon := &http.Cookie{Name: csrfCookie, Value: "navegador-1"}
+	player := &http.Cookie{Name: sessionCookie, Value: h.Sessions.Issue("ana-id", "Ana")}
+
+	cases := []struct {
+		name   string
+		cookie *http.Cookie
+		header string
+		form   string
+		want   int
+	}{
+		{"sin token", anon, "", "", http.StatusForbidden},
+		{"sin cookie", nil, h.Sessions.CSRFToken("navegador-1"), "", http.StatusForbidden},
+		{"token de otro navegador", anon, h.Sessions.CSRFToken("navegador-2"), "", http.StatusForbidden},
+		{"token en el header", anon, h.Sessions.CSRFToken("navegador-1"), "", http.StatusOK},
+		{"token en el formulario", anon, "", h.Sessions.CSRFToken("navegador-1"), http.StatusOK},
+		{"con sesion, token del jugador", player, h.Sessions.CSRFToken("ana-id"), "", http.StatusOK},
+		{"con sesion, token anonimo", player, h.Sessions.CSRFToken("navegador-1"), "", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		body := url.Values{"player_name": {"Ana"}}
+		if c.form != "" {
+			body.Set(csrfField, c.form)
+		}
+		req := httptest.NewRequest("POST", "/create-room", strings.NewReader(body.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if c.cookie != nil {
+			req.AddCookie(c.cookie)
+		}
+		if c.header != "" {
+			req.Header.Set(csrfHeader, c.header)
+		}
+
+		rec := httptest.NewRecorder()
+		csrfServer(h).ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("%s: status %d, se esperaba %d", c.name, rec.Code, c.want)
+		}
+	}
+}
+
+// Los clientes de la API no llevan token: Authorization o un cuerpo JSON no se pueden
+// mandar desde un formulario de otro sitio
+func TestCSRFSkipsAPIClients(t *testing.T) {
+	h := newTestHandler(t, config.Default())
+
+	bearer := httptest.NewRequest("POST", "/api/v1/rooms/abcde/bet", nil)
+	bearer.Header.Set("Authorization", "Bearer x")
+	jsonBody := httptest.NewRequest("POST", "/api/v1/rooms", strings.NewReader(`{}`))
+	jsonBody.Header.Set("Content-Type", "application/json; charset=utf-8")
+	form := httptest.NewRequest("POST", "/api/v1/rooms", strings.NewReader(`a=1`))
+	form.Header.Set("Content-Type", "text/plain")
+
+	for req, want := range map[*http.Request]int{bearer: http.StatusOK, jsonBody: http.StatusOK, form: http.StatusForbidden} {
+		rec := httptest.NewRecorder()
+		csrfServer(h).ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s con %q: status %d, se esperaba %d", req.URL.Path, req.Header.Get("Content-Type"), rec.Code, want)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	cfg := config.Default()
+	cfg.AllowedOrigins = []string{"https://amigos.example"}
+	ws := &WSHandler{GameH: newTestHandler(t, cfg)}
+
+	cases := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true}, // no es un navegador
+		{"http://dados.example", true},
+		{"https://DADOS.example", true},
+		{"https://amigos.example", true},
+		{"https://AMIGOS.example", true},
+		{"https://malo.example", false},
+		{"https://dados.example.malo.example", false},
+		{"null", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "http://dados.example/ws/abcde", nil)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		if got := ws.checkOrigin(req); got != c.want {
+			t.Errorf("Origin %q: checkOrigin = %t, se esperaba %t", c.origin, got, c.want)
+		}
+	}
+
+	cfg.AllowedOrigins = []string{"*"}
+	ws = &WSHandler{GameH: newTestHandler(t, cfg)}
+	req := httptest.NewRequest("GET", "http://dados.example/ws/abcde", nil)
+	req.Header.Set("Origin", "https://cualquiera.example")
+	if !ws.checkOrigin(req) {
+		t.Error("con allowed_origins=* se deberia aceptar cualquier origen")
+	}
+}
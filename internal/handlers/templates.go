@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"dados-mentirosos/ui"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// templateFuncs son las funciones disponibles en todos los templates
+var templateFuncs = template.FuncMap{
+	"toInt": func(i any) int {
+		switch v := i.(type) {
+		case game.Dice:
+			return int(v)
+		case int:
+			return v
+		default:
+			return 0
+		}
+	},
+	"languages": func() []i18n.Language { return i18n.Languages },
+	"reactions": func() []string { return game.Reactions },
+}
+
+// localeFuncs agrega a templateFuncs las funciones atadas al idioma del set:
+// {{t "clave" args...}} traduce y {{lang}} devuelve el codigo del idioma
+func localeFuncs(lang string) template.FuncMap {
+	funcs := template.FuncMap{
+		"t":    func(key string, args ...any) string { return i18n.T(lang, key, args...) },
+		"lang": func() string { return lang },
+	}
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// Templates parsea todos los templates una sola vez al arrancar.
+// Los partials (y base.html) quedan en un set compartido; cada pagina define su
+// propio "content", asi que tiene su copia del set con la pagina agregada.
+// Hay un juego de sets por idioma, con la funcion "t" de ese idioma.
+type Templates struct {
+	mutex   sync.RWMutex
+	fsys    fs.FS
+	locales map[string]*localeSet
+
+	// modo desarrollo: se vuelve a parsear cuando cambia algun archivo
+	dev      bool
+	parsedAt time.Time
+}
+
+// NewTemplates carga los templates embebidos o, si dir no esta vacio, los del disco.
+// Con dev=true se releen del disco cada vez que cambian.
+func NewTemplates(dir string, dev bool) (*Templates, error) {
+	var fsys fs.FS
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	} else {
+		sub, err := fs.Sub(ui.HTML, "html")
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	t := &Templates{fsys: fsys, dev: dev}
+	if err := t.parse(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// localeSet son los templates parseados para un idioma
+type localeSet struct {
+	partials *template.Template
+	pages    map[string]*template.Template
+}
+
+// parse arma los sets de templates de todos los idiomas desde cero
+func (t *Templates) parse() error {
+	locales := make(map[string]*localeSet)
+	for _, lang := range i18n.Languages {
+		set, err := t.parseLocale(lang.Code)
+		if err != nil {
+			return err
+		}
+		locales[lang.Code] = set
+	}
+
+	t.mutex.Lock()
+	t.locales = locales
+	t.parsedAt = time.Now()
+	t.mutex.Unlock()
+	return nil
+}
+
+// parseLocale arma los sets de un idioma
+func (t *Templates) parseLocale(lang string) (*localeSet, error) {
+	partialFiles, err := fs.Glob(t.fsys, "partials/*/*.html")
+	if err != nil {
+		return nil, err
+	}
+	topPartials, err := fs.Glob(t.fsys, "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	partialFiles = append(append([]string{"base.html"}, topPartials...), partialFiles...)
+
+	partials, err := template.New("partials").Funcs(localeFuncs(lang)).ParseFS(t.fsys, partialFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("parseando partials: %w", err)
+	}
+
+	pageFiles, err := fs.Glob(t.fsys, "pages/*.html")
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]*template.Template)
+	for _, file := range pageFiles {
+		// se clona antes de ejecutar nada: html/template no deja clonar despues
+		clone, err := partials.Clone()
+		if err != nil {
+			return nil, err
+		}
+		page, err := clone.ParseFS(t.fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("parseando %s: %w", file, err)
+		}
+		pages[path.Base(file)] = page
+	}
+	return &localeSet{partials: partials, pages: pages}, nil
+}
+
+// locale devuelve los sets del idioma (o los del idioma por defecto si no hay)
+func (t *Templates) locale(lang string) *localeSet {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if set, ok := t.locales[lang]; ok {
+		return set
+	}
+	return t.locales[i18n.Default]
+}
+
+// reloadIfChanged vuelve a parsear si algun archivo cambio (solo en modo dev)
+func (t *Templates) reloadIfChanged() {
+	if !t.dev {
+		return
+	}
+
+	t.mutex.RLock()
+	parsedAt := t.parsedAt
+	t.mutex.RUnlock()
+
+	changed := false
+	fs.WalkDir(t.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(p, ".html") {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(parsedAt) {
+			changed = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if !changed {
+		return
+	}
+
+	if err := t.parse(); err != nil {
+		slog.Error("error recargando templates", "err", err)
+		return
+	}
+	slog.Info("templates recargados")
+}
+
+// Check indica si los templates estan cargados (lo usa /readyz)
+func (t *Templates) Check() error {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	for _, lang := range i18n.Languages {
+		set, ok := t.locales[lang.Code]
+		if !ok || set.partials.Lookup("base") == nil || len(set.pages) == 0 {
+			return fmt.Errorf("templates sin cargar (%s)", lang.Code)
+		}
+	}
+	return nil
+}
+
+// ExecutePage ejecuta un template del set de una pagina ("base" para la pagina completa)
+// en el idioma pedido
+func (t *Templates) ExecutePage(w io.Writer, lang, page, name string, data any) error {
+	t.reloadIfChanged()
+
+	tmpl, ok := t.locale(lang).pages[page]
+	if !ok {
+		return fmt.Errorf("pagina %q no encontrada", page)
+	}
+	defer templateRender.Since(time.Now(), page)
+	return tmpl.ExecuteTemplate(w, name, data)
+}
+
+// Fragment ejecuta un partial en el idioma pedido y devuelve el HTML (escapado por html/template)
+func (t *Templates) Fragment(lang, name string, data any) (string, error) {
+	t.reloadIfChanged()
+
+	tmpl := t.locale(lang).partials
+
+	defer templateRender.Since(time.Now(), name)
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, name, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
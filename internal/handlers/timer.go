@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"log/slog"
+	"time"
+)
+
+// timerTick es cada cuanto se manda el tiempo que le queda al turno
+const timerTick = time.Second
+
+// La cuenta regresiva la lleva el servidor: la pantalla trae el tiempo al dibujarse y
+// despues, mientras el turno tenga limite, cada sesion recibe un fragmento chico que
+// reemplaza #turn-timer (o el evento "timer" en JSON). Asi todos ven el mismo numero,
+// tambien despues de reconectarse, sin redibujar la pantalla.
+
+// broadcastTimer manda a toda la sala el tiempo que le queda al turno en curso
+func (h *WSHandler) broadcastTimer(room *game.Room) {
+	sessions := h.hub.sessions(room.ID)
+	if len(sessions) == 0 {
+		return
+	}
+	view := room.SpectatorView()
+	if view.TurnDeadline.IsZero() {
+		return // no hay partida o el turno no tiene limite
+	}
+
+	defer broadcastLatency.Since(time.Now(), "timer")
+
+	// el fragmento no tiene textos, se arma una sola vez para todos
+	var html []byte
+	for _, s := range sessions {
+		if isJSONSession(s) {
+			s.Write(encodeServerMessage("event", "", map[string]any{
+				"event":             "timer",
+				"current_player_id": view.CurrentPlayerID,
+				"turn_deadline":     view.TurnDeadline,
+				"seconds_left":      view.SecondsLeft,
+			}))
+			continue
+		}
+		if html == nil {
+			out, err := h.GameH.renderFragment(sessionLang(s), "turn_timer", view)
+			if err != nil {
+				slog.Error("error renderizando template", "template", "turn_timer", "room_id", room.ID, "err", err)
+				return
+			}
+			html = []byte(out)
+		}
+		s.Write(html)
+	}
+}
@@ -2,15 +2,20 @@ package handlers
 
 import (
 	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"dados-mentirosos/internal/ratelimit"
 	"fmt"
-	"bytes"
-	"html/template"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/gorilla/websocket"
 	"github.com/olahol/melody"
 )
 
@@ -18,37 +23,80 @@ type WSHandler struct {
 	Manager   *game.GameManager
 	Melody    *melody.Melody
 	GameH     *GameHandler
+
+	hub        *sessionHub // sesiones conectadas, por sala
+	watchMutex sync.Mutex
+	watching   map[string]bool // salas cuyas actualizaciones ya se estan reenviando
+	leaveMutex sync.Mutex
+	leaving    map[string]*time.Timer // jugadores desconectados a mitad de partida (ver leaveRoom)
 }
 
+// reconnectGrace es cuanto se le guarda el asiento a quien se desconecta con la partida
+// en curso. Si vuelve en ese tiempo sigue jugando con sus dados.
+const reconnectGrace = 30 * time.Second
+
+// lobbyGrace es lo mismo en el lobby: alcanza para recargar la pagina sin perder el
+// lugar (ni el host), sin dejar por mucho tiempo asientos de quien se fue
+const lobbyGrace = 5 * time.Second
+
 func NewWSHandler(m *melody.Melody, gm *game.GameManager, gh *GameHandler) *WSHandler {
 	handler := &WSHandler{
 		Manager: gm, 
 		Melody:  m,
 		GameH:   gh,
+		hub:      newSessionHub(),
+		watching: make(map[string]bool),
+		leaving:  make(map[string]*time.Timer),
 	}
 
+	// Los clientes que no son navegador negocian el protocolo JSON
+	handler.Melody.Upgrader.Subprotocols = []string{JSONProtocol}
+	// Solo el mismo origen (o los configurados) pueden abrir el socket desde un navegador
+	handler.Melody.Upgrader.CheckOrigin = handler.checkOrigin
+	handler.Melody.HandleMessage(handler.handleMessage)
+	handler.Melody.HandleError(handler.handleError)
+	handler.Melody.HandleSentMessage(countSent)
+
 	// Cuando alguien se conecta
 	handler.Melody.HandleConnect(func(s *melody.Session) {
 		roomID := s.MustGet("roomID").(string)
 		playerID := s.MustGet("playerID").(string)
 		playerName := s.MustGet("playerName").(string)
 
-		fmt.Printf("Jugador %s conectado a sala %s\n", playerName, roomID)
+		sessionLogger(s).Info("jugador conectado", "name", playerName, "protocol", s.MustGet("protocol"))
 
 		room, err := handler.Manager.GetRoom(roomID)
 		if err == nil {
-			if room.OnUpdate == nil {
-				room.OnUpdate = handler.broadcastGameState
-			}
+			handler.watch(room)
 			newPlayer := &game.Player{
 				ID:   playerID,
 				Name: playerName,
 			}
-			room.AddPlayer(newPlayer)
-			
-			handler.BroadcastPlayerList(roomID)
-			htmlState := handler.generateLobbyHTML(room, playerID)
-			s.Write([]byte(htmlState))
+			// Solo se queda conectado quien se sento o ya estaba sentado (se reconecta);
+			// cualquier otro error cierra el socket antes de mandarle estado o chat
+			err := room.AddPlayer(newPlayer)
+			if err != nil && !errors.Is(err, game.ErrPlayerExist) {
+				if isJSONSession(s) {
+					handler.writeJSONError(s, "", err)
+				} else {
+					lang := sessionLang(s)
+					s.Write([]byte(handler.errorBannerHTML(lang, translateError(lang, "", err).Body.Message)))
+				}
+				s.Close()
+				return
+			}
+
+			handler.hub.add(roomID, s)
+			handler.cancelLeave(roomID, playerID) // volvio a tiempo
+
+			// El resto de la sala se entera por la actualizacion "join". Al que entra se le
+			// manda la pantalla que corresponda (si la partida ya arranco, el tablero)
+			handler.writeState(s, room, playerID)
+			handler.writeChatHistory(s, room, playerID)
+			if message := gh.Announcement(); message != "" && isJSONSession(s) {
+				// el navegador ya lo recibio con la pagina
+				s.Write(encodeServerMessage("event", "", map[string]string{"event": "announcement", "message": message}))
+			}
 		}
 	})
 
@@ -57,514 +105,370 @@ func NewWSHandler(m *melody.Melody, gm *game.GameManager, gh *GameHandler) *WSHa
 		roomID := s.MustGet("roomID").(string)
 		playerID := s.MustGet("playerID").(string)
 
-		room, err := handler.Manager.GetRoom(roomID)
-		if err == nil {
-			room.RemovePlayer(playerID)
-			handler.BroadcastPlayerList(roomID)
+		handler.hub.remove(roomID, s)
+		sessionLogger(s).Info("jugador desconectado", "connections", handler.hub.count(roomID))
+		if handler.Manager.IsDraining() {
+			return // se cierran todas por el apagado: los jugadores vuelven con la sala guardada
+		}
+		if _, closed := s.Get("closedBy"); closed {
+			return // la cerro el servidor (ver closeSessions): no hay asiento que liberar aca
 		}
+		if handler.hub.playerSessions(roomID, playerID) > 0 {
+			return // sigue conectado desde otra pestaña o conexion
+		}
+		handler.leaveRoom(roomID, playerID)
 	})
 
 	return handler
 }
 
-// HandleRequest es el endpoint HTTP que transforma la conexion en WebSocket
-func (h *WSHandler) HandleRequest(w http.ResponseWriter, r *http.Request) {
-	roomID := chi.URLParam(r, "roomID")
-	
-	// Leer la cookie para saber quién es
-	cookie, err := r.Cookie("player_id")
+// leaveRoom saca de la sala al jugador que cerro su ultima conexion, si no vuelve antes
+// de lobbyGrace (en el lobby) o reconnectGrace (con la partida en curso o en los resultados)
+func (h *WSHandler) leaveRoom(roomID, playerID string) {
+	room, err := h.Manager.GetRoom(roomID)
 	if err != nil {
-		http.Error(w, "No autorizado", http.StatusUnauthorized)
 		return
 	}
+	grace := reconnectGrace
+	if room.SpectatorView().Status == "WAITING" {
+		grace = lobbyGrace
+	}
+
+	key := roomID + "|" + playerID
+	h.leaveMutex.Lock()
+	defer h.leaveMutex.Unlock()
+	if timer, ok := h.leaving[key]; ok {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(grace, func() {
+		h.leaveMutex.Lock()
+		current := h.leaving[key] == timer
+		if current {
+			delete(h.leaving, key)
+		}
+		h.leaveMutex.Unlock()
+
+		if !current || h.hub.playerSessions(roomID, playerID) > 0 {
+			return
+		}
+		if room, err := h.Manager.GetRoom(roomID); err == nil {
+			room.RemovePlayer(playerID) // publica "leave"
+		}
+	})
+	h.leaving[key] = timer
+}
+
+// cancelLeave le devuelve el asiento a un jugador que se reconecto dentro de reconnectGrace
+func (h *WSHandler) cancelLeave(roomID, playerID string) {
+	key := roomID + "|" + playerID
+	h.leaveMutex.Lock()
+	defer h.leaveMutex.Unlock()
+	if timer, ok := h.leaving[key]; ok {
+		timer.Stop()
+		delete(h.leaving, key)
+	}
+}
+
+// HandleRequest es el endpoint HTTP que transforma la conexion en WebSocket
+func (h *WSHandler) HandleRequest(w http.ResponseWriter, r *http.Request) {
+	roomID := chi.URLParam(r, "roomID")
 	
-	// Parsear "ID:Nombre" (que se guardo en http.go)
-	parts := strings.Split(cookie.Value, ":")
-	if len(parts) != 2 {
-		http.Error(w, "Cookie inválida", http.StatusBadRequest)
+	// Identificar al jugador con su sesion firmada (cookie, Bearer o ?token=)
+	sess, ok := h.GameH.wsSession(r)
+	if !ok {
+		http.Error(w, i18n.T(locale(r), "error.unauthorized"), http.StatusUnauthorized)
 		return
 	}
-	playerID := parts[0]
-	playerName := parts[1]
+	playerID := sess.PlayerID
+	playerName := sess.Name
 
 	// Pasamos datos a la sesión de Melody para usarlos en HandleConnect
 	keys := map[string]interface{}{
 		"roomID":     roomID,
 		"playerID":   playerID,
 		"playerName": playerName,
+		"protocol":   requestedProtocol(r),
+		"lang":       locale(r), // los fragmentos y errores de esta conexion salen en este idioma
+		"ip":         clientIP(r),
+		"requestID":  middleware.GetReqID(r.Context()), // para seguir la conexion en los logs
+	}
+	if h.GameH.Config.WSRate > 0 {
+		keys["rate"] = ratelimit.NewBucket(float64(h.GameH.Config.WSRate), 2*h.GameH.Config.WSRate)
 	}
 
+	setRoomOwner(w, h.Manager, roomID)
 	h.Melody.HandleRequestWithKeys(w, r, keys)
 }
 
-// BroadcastPlayerList genera el HTML de la lista y lo envía a todos en la sala
-func (h *WSHandler) BroadcastPlayerList(roomID string) {
-	room, err := h.Manager.GetRoom(roomID)
-	if err != nil {
+// watch se suscribe a las actualizaciones de la sala (una sola vez por sala) y las
+// reenvia a las sesiones conectadas. Asi cualquier cambio, venga del WebSocket, de la
+// API o de un timer, llega a todos en el orden en que la sala lo proceso.
+// Si se acumularon varias actualizaciones se dibuja una sola vez con el estado mas nuevo.
+// Entre actualizaciones manda la cuenta regresiva del turno (ver timer.go).
+func (h *WSHandler) watch(room *game.Room) {
+	h.watchMutex.Lock()
+	defer h.watchMutex.Unlock()
+	if h.watching[room.ID] {
 		return
 	}
+	h.watching[room.ID] = true
+
+	updates, _ := room.Subscribe()
+	go func() {
+		ticker := time.NewTicker(timerTick)
+		defer ticker.Stop()
+
+		for open := true; open; {
+			select {
+			case update, ok := <-updates:
+				if !ok {
+					open = false
+					break
+				}
+				batch := []game.RoomUpdate{update}
+			pending:
+				for {
+					select {
+					case next, ok := <-updates:
+						if !ok {
+							break pending
+						}
+						batch = append(batch, next)
+					default:
+						break pending
+					}
+				}
+				h.dispatch(room, batch)
+			case <-ticker.C:
+				h.broadcastTimer(room)
+			}
+		}
+
+		// la sala se cerro
+		h.watchMutex.Lock()
+		delete(h.watching, room.ID)
+		h.watchMutex.Unlock()
+	}()
+}
 
-	var listBuilder strings.Builder
-	listBuilder.WriteString(`<ul id="players-list" hx-swap-oob="true" class="space-y-2 min-h-[100px]">`)
-	for _, p := range room.Players {
-		hostBadge := ""
-		if p.IsHost {
-			hostBadge = "👑"
+// dispatch reenvia un grupo de actualizaciones: la pantalla se dibuja una sola vez con el
+// estado mas nuevo y el chat va aparte, sin redibujar la pantalla
+func (h *WSHandler) dispatch(room *game.Room, batch []game.RoomUpdate) {
+	full, players, chatLog := false, false, false
+	var messages []game.ChatMessage
+	var reactions []game.Reaction
+	for _, update := range batch {
+		switch update.Event {
+		case "chat":
+			if update.Chat != nil {
+				messages = append(messages, *update.Chat)
+			}
+		case "react":
+			if update.Reaction != nil {
+				reactions = append(reactions, *update.Reaction)
+			}
+		case "clear-chat":
+			chatLog = true
+		case "mute":
+			players, chatLog = true, true
+		case "join", "ready":
+			players = true
+		case "leave":
+			players, chatLog = true, true // puede haber cambiado el host
+		case "sync":
+			full, chatLog = true, true // se pudieron perder mensajes del chat
+		default:
+			full = true
 		}
-		listBuilder.WriteString(fmt.Sprintf(
-			`<li class="bg-slate-700 p-2 rounded flex justify-between items-center animate-fade-in">
-                <span class="font-bold text-slate-200">%s</span>
-                <span>%s</span>
-            </li>`,
-			p.Name, hostBadge))
 	}
-	listBuilder.WriteString("</ul>")
 
-	playersListHTML := listBuilder.String()
-	tmpl, err := template.ParseFiles("ui/html/partials/lobby/controls.html")
+	if full {
+		h.broadcastGameState(room.ID)
+	} else if players {
+		h.BroadcastPlayerList(room.ID) // en el lobby alcanza con la lista
+	}
+	if chatLog || len(messages) > 0 {
+		h.broadcastChat(room, messages, chatLog)
+	}
+	if len(reactions) > 0 {
+		h.broadcastReactions(room, reactions)
+	}
+}
+
+// setRoomOwner informa que instancia tiene la sala, para que el balanceador pueda
+// mandar los proximos pedidos de la sala directo a ella
+func setRoomOwner(w http.ResponseWriter, gm *game.GameManager, roomID string) {
+	if owner := gm.Owner(roomID); owner != "" {
+		w.Header().Set("X-Room-Owner", owner)
+	}
+}
+
+// BroadcastPlayerList genera el HTML de la lista y lo envía a todos en la sala
+func (h *WSHandler) BroadcastPlayerList(roomID string) {
+	room, err := h.Manager.GetRoom(roomID)
 	if err != nil {
-		fmt.Printf("Error parseando controles: %v\n", err)
 		return
 	}
 
-	sessions, _ := h.Melody.Sessions() 
-	for _, s := range sessions {
-		if sID, exists := s.Get("roomID"); !exists || sID.(string) != roomID {
+	defer broadcastLatency.Since(time.Now(), "players")
+
+	// La lista sale del mismo template que el lobby, asi los nombres siempre pasan por html/template.
+	// Se arma una vez por idioma.
+	view := room.SpectatorView()
+	playersLists := make(map[string]string)
+
+	for _, s := range h.hub.sessions(roomID) {
+		playerID := s.MustGet("playerID").(string)
+		if h.resync(s, room, playerID) {
 			continue
 		}
-		pID, exists := s.Get("playerID")
-		if !exists {
+		if isJSONSession(s) {
+			h.writeJSONState(s, room, playerID)
 			continue
 		}
-		playerID := pID.(string)
-		isHost := false
-		if p, ok := room.Players[playerID]; ok {
-			isHost = p.IsHost
+		lang := sessionLang(s)
+		playersListHTML, ok := playersLists[lang]
+		if !ok {
+			playersListHTML, err = h.GameH.renderFragment(lang, "players_list", map[string]interface{}{
+				"Players": view.Seats,
+				"OOB":     true,
+			})
+			if err != nil {
+				slog.Error("error renderizando template", "template", "players_list", "room_id", roomID, "err", err)
+				return
+			}
+			playersLists[lang] = playersListHTML
 		}
-
-		var controlsBuffer bytes.Buffer
 		data := map[string]interface{}{
 			"RoomID": roomID,
-			"IsHost": isHost, 
+			"IsHost": room.IsHost(playerID), 
 		}
 		
-		err := tmpl.ExecuteTemplate(&controlsBuffer, "lobby_controls", data)
+		controlsHTML, err := h.GameH.renderFragment(lang, "lobby_controls", data)
 		if err != nil {
-			fmt.Printf("Error exec template: %v\n", err)
+			slog.Error("error renderizando template", "template", "lobby_controls", "room_id", roomID, "err", err)
 			continue
 		}
-		fullMessage := playersListHTML + "\n" + controlsBuffer.String()
+		fullMessage := playersListHTML + "\n" + controlsHTML
 		s.Write([]byte(fullMessage))
 	}
 }
 
-// StartGameAndBroadcast inicia el juego y notifica a todos con sus tableros únicos
-func (h *WSHandler) StartGameAndBroadcast(roomID string) error {
-	room, err := h.Manager.GetRoom(roomID)
-	if err != nil {
-		return err
-	}
-	// Buscamos el ID del Host para iniciar la partida
-	var hostID string
-	for _, p := range room.Players {
-		if p.IsHost {
-			hostID = p.ID
-			break
-		}
-	}
-	err = room.StartGame(hostID)
-	if err != nil {
-		return err
-	}
-	h.broadcastGameState(roomID)
-	return nil
-}
-
 func (h *WSHandler) broadcastGameState(roomID string) {
 	room, err := h.Manager.GetRoom(roomID)
 	if err != nil {
 		return
 	}
 
-	sessions, _ := h.Melody.Sessions()
-	for _, s := range sessions {
-		sRoomID, exists := s.Get("roomID")
-		if !exists || sRoomID.(string) != roomID {
-			continue
-		}
+	defer broadcastLatency.Since(time.Now(), "state")
 
-		playerID, _ := s.Get("playerID")
-		var htmlState string
-		switch room.Status{
-			case "WAITING":
-				htmlState = h.generateLobbyHTML(room, playerID.(string))
-			case "FINISHED":
-				htmlState = h.generateResultsHTML(room, playerID.(string))
-			case "PLAYING":
-				htmlState = h.generateGameScreenHTML(room, playerID.(string))
+	for _, s := range h.hub.sessions(roomID) {
+		playerID := s.MustGet("playerID").(string)
+		if h.resync(s, room, playerID) {
+			continue // ademas del estado recibe el chat que se perdio
 		}
-		s.Write([]byte(htmlState))
+		h.writeState(s, room, playerID)
 	}
 }
 
-// Helper para rellenar la plantilla
-func (h *WSHandler) generateGameScreenHTML(room *game.Room, myPlayerID string) string {
-	
-	me := room.Players[myPlayerID]
-
-	if room.Status == "FINISHED" && room.LastResult != nil {
-		return h.generateResultsHTML(room, myPlayerID)
-	}
-
-
-	currentPlayerName := "???"
-	if p, ok := room.Players[room.State.CurrentPlayerID]; ok {
-		currentPlayerName = p.Name
-	}
-
-	type OpponentView struct {
-		Name      string
-		DiceCount int
-		IsTurn    bool
-	}
-	var opponents []OpponentView
-	for _, p := range room.Players {
-		if p.ID != myPlayerID {
-			opponents = append(opponents, OpponentView{
-				Name:      p.Name,
-				DiceCount: len(p.Dice),
-				IsTurn:    (p.ID == room.State.CurrentPlayerID),
-			})
-		}
-	}
-
-	lastBetPlayerName := "Nadie"
-	if p, ok := room.Players[room.State.LastBetPlayerID]; ok {
-		lastBetPlayerName = p.Name
-	}
-
-	secondsLeft := 0
-		if !room.TurnDeadline.IsZero() {
-    	remaining := time.Until(room.TurnDeadline)
-    	if remaining > 0 {
-        	secondsLeft = int(remaining.Seconds())
-    	}
-	}
-
-	data := map[string]interface{}{
-		"RoomID":            room.ID,
-		"IsMyTurn":          (room.State.CurrentPlayerID == myPlayerID),
-		"CurrentPlayerName": currentPlayerName,
-		"CurrentBetQty":     room.State.CurrentBetQuantity,
-		"CurrentBetFace":    room.State.CurrentBetFace,
-		"LastBetPlayer":     lastBetPlayerName,
-		"MyDice":            me.Dice,
-		"Opponents":         opponents,
-		"SecondsLeft":       secondsLeft,
-	}
-
-	// Cargar los templates necesarios aquí mismo
-	files := []string{
-		"ui/html/partials/game/screen.html",   // El tablero
-		"ui/html/partials/game/controls.html", // Los botones
-	}
-
-	// Usamos "html/template"
-	tmpl, err := template.ParseFiles(files...)
-	if err != nil {
-		fmt.Println("Error cargando templates WS:", err)
-		return `<div class="text-red-500">Error interno cargando el juego</div>`
+// generateStateHTML elige la pantalla segun el estado de la sala.
+// Todas las pantallas se arman desde la vista del jugador, que ya viene sin los dados ajenos.
+func (h *WSHandler) generateStateHTML(room *game.Room, playerID, lang string) string {
+	view := viewFor(room, playerID)
+	switch view.Status{
+		case "FINISHED":
+			return h.generateResultsHTML(view, lang)
+		case "PLAYING":
+			return h.generateGameScreenHTML(view, lang)
+		default:
+			return h.generateLobbyHTML(view, lang)
 	}
+}
 
-	// Renderizar a String
-	var out strings.Builder
-	err = tmpl.ExecuteTemplate(&out, "game_screen", data)
+// viewFor devuelve la vista del jugador o, si no esta sentado en la sala, la de espectador
+func viewFor(room *game.Room, playerID string) game.PlayerView {
+	view, err := room.PlayerView(playerID)
 	if err != nil {
-		fmt.Println("Error renderizando game_screen:", err)
-		return `<div class="text-red-500">Error renderizando el juego</div>`
+		return game.PlayerView{SpectatorView: room.SpectatorView()}
 	}
-
-	return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML">%s</div>`, out.String())
+	return view
 }
 
-func (h *WSHandler) generateResultsHTML(room *game.Room, myPlayerID string) string {
-    // Debug: Avisar que intentamos generar resultados
-    fmt.Printf("Generando pantalla de resultados para %s...\n", myPlayerID)
-
-    playersList := make([]*game.Player, 0)
-    for _, p := range room.Players {
-        playersList = append(playersList, p)
-    }
-
-    funcMap := template.FuncMap{
-        "toInt": func(i interface{}) int {
-            switch v := i.(type) {
-            case game.Dice:
-                return int(v)
-            case int:
-                return v
-            default:
-                return 0
-            }
-        },
-    }
-    data := map[string]interface{}{
-        "RoomID":  room.ID,
-        "MyID":    myPlayerID,
-        "Result":  room.LastResult,
-        "Players": playersList,
-        "IsHost":  room.Players[myPlayerID].IsHost,
-		"Config":  room.Config,
-    }
-
-    // Asegurarse de que la ruta es correcta
-    files := []string{"ui/html/partials/game/results.html"}
-    
-    // Parsear
-    tmpl, err := template.New("results_screen").Funcs(funcMap).ParseFiles(files...)
-    if err != nil {
-        // ERROR CRÍTICO 1: No se encontró el archivo o falló el parseo
-        fmt.Printf("ERROR ParseFiles Results: %v\n", err)
-        return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML" class="bg-red-900 p-4 text-white">ERROR TEMPLATE: %v</div>`, err)
-    }
-
-    var out strings.Builder
-    err = tmpl.ExecuteTemplate(&out, "results_screen", data)
-    if err != nil {
-        // ERROR CRÍTICO 2: Falló al ejecutar (variable faltante, función mal llamada)
-        fmt.Printf("ERROR ExecuteTemplate Results: %v\n", err)
-        return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML" class="bg-red-900 p-4 text-white">ERROR EXEC: %v</div>`, err)
-    }
+// BroadcastShutdown avisa a todas las salas que el servidor se va a reiniciar
+func (h *WSHandler) BroadcastShutdown() {
+	for _, l := range i18n.Languages {
+		lang := l.Code
+		banner := `<div id="server-banner" hx-swap-oob="true" class="fixed top-0 left-0 w-full z-50 bg-yellow-500 text-slate-900 text-center text-sm font-bold py-2 shadow-lg">
+        ⚠️ ` + i18n.T(lang, "banner.restarting") + `
+    </div>`
+		h.Melody.BroadcastFilter([]byte(banner), func(s *melody.Session) bool {
+			return !isJSONSession(s) && sessionLang(s) == lang
+		})
+	}
+	h.Melody.BroadcastFilter(encodeServerMessage("event", "", map[string]string{"event": "server_restarting"}), isJSONSession)
+}
 
-    fmt.Println("HTML Resultados generado correctamente")
-    return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML">%s</div>`, out.String())
+// Close cierra todas las conexiones WebSocket avisando el motivo
+func (h *WSHandler) Close() error {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "servidor reiniciando")
+	return h.Melody.CloseWithMsg(msg)
 }
 
-func (h *WSHandler) generateLobbyHTML(room *game.Room, playerID string) string {
-	// Reutilizamos el archivo lobby.html que ya creamos
-	files := []string{"ui/html/pages/lobby.html","ui/html/partials/lobby/settings.html","ui/html/partials/lobby/controls.html"}
-	
-	tmpl, err := template.ParseFiles(files...)
+// generateGameScreenHTML arma el tablero con la vista del jugador
+func (h *WSHandler) generateGameScreenHTML(view game.PlayerView, lang string) string {
+	// Renderizar a String (el tablero incluye los controles)
+	out, err := h.GameH.renderFragment(lang, "game_screen", view)
 	if err != nil {
-		return fmt.Sprintf("Error template lobby: %v", err)
+		slog.Error("error renderizando template", "template", "game_screen", "room_id", view.RoomID, "player_id", view.MyID, "err", err)
+		return h.errorBannerHTML(lang, i18n.T(lang, "error.render_game"))
 	}
 
-	isHost := false
-	if p, ok := room.Players[playerID]; ok {
-		isHost = p.IsHost
-	}
-
-	data := map[string]interface{}{
-		"RoomID": room.ID,
-		"Config": room.Config,
-		"IsHost": isHost,
-		"Players": room.Players,
-	}
-
-	var out strings.Builder
-	err = tmpl.ExecuteTemplate(&out, "content", data)
-	if err != nil {
-		return fmt.Sprintf("Error exec lobby: %v", err)
-	}
-	return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML">%s</div>`, out.String())
+	return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML">%s</div>`, out)
 }
 
-func (h *WSHandler) HandleStartGame(w http.ResponseWriter, r *http.Request) {
-    // Obtener datos de cookie
-    cookie, _ := r.Cookie("player_id")
-    parts := strings.Split(cookie.Value, ":")
-    playerID := parts[0]
-    
-    // Obtener RoomID 
-    roomID := r.URL.Query().Get("roomID")
-
-    // Validar host
-    room, err := h.Manager.GetRoom(roomID)
-    if err != nil {
-        http.Error(w, "Sala no encontrada", http.StatusNotFound)
-        return
-    }
-    
-    // Verificacion rapida de host
-    isHost := false
-    for _, p := range room.Players {
-        if p.ID == playerID && p.IsHost { isHost = true; break }
-    }
-    if !isHost {
-        http.Error(w, "Solo el host puede iniciar", http.StatusForbidden)
-        return
-    }
-
-    // 4. Iniciar y Broadcast
-    err = h.StartGameAndBroadcast(roomID)
+func (h *WSHandler) generateResultsHTML(view game.PlayerView, lang string) string {
+    out, err := h.GameH.renderFragment(lang, "results_screen", view)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
+        // Falló al ejecutar (variable faltante, función mal llamada)
+        slog.Error("error renderizando template", "template", "results_screen", "room_id", view.RoomID, "player_id", view.MyID, "err", err)
+        return h.errorBannerHTML(lang, i18n.T(lang, "error.render_results"))
     }
 
-    w.WriteHeader(http.StatusOK)
+    return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML">%s</div>`, out)
 }
 
-func (h *WSHandler) HandleBet(w http.ResponseWriter, r *http.Request) {
-	// Identificar al jugador
-	cookie, _ := r.Cookie("player_id")
-	parts := strings.Split(cookie.Value, ":")
-	plaryerID := parts[0]
-
-	roomID := r.URL.Query().Get("roomID")
-
-	room, err := h.Manager.GetRoom(roomID)
-	if err != nil {
-		http.Error(w, "sala no encontrada", http.StatusNotFound)
-		return
-	}
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "datos invalidos", http.StatusBadRequest)
-		return
+func (h *WSHandler) generateLobbyHTML(view game.PlayerView, lang string) string {
+	data := map[string]interface{}{
+		"RoomID": view.RoomID,
+		"Config": view.Config,
+		"IsHost": view.IsHost,
+		"Players": view.Seats,
 	}
 
-	quantity := atoi(r.FormValue("quantity"))
-	face := atoi(r.FormValue("face"))
-
-	err = room.PlaceBet(plaryerID, quantity, face)
+	// Reutilizamos el "content" de la pagina lobby.html
+	var out strings.Builder
+	err := h.GameH.Templates.ExecutePage(&out, lang, "lobby.html", "content", data)
 	if err != nil {
-		w.Header().Set("HX-Retarget", "#bet-error")
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		slog.Error("error renderizando template", "template", "lobby.html", "room_id", view.RoomID, "player_id", view.MyID, "err", err)
+		return h.errorBannerHTML(lang, i18n.T(lang, "error.render_lobby"))
 	}
-	h.broadcastGameState(roomID)
-	w.WriteHeader(http.StatusOK)
+	return fmt.Sprintf(`<div id="content" hx-swap-oob="innerHTML">%s</div>`, out.String())
 }
 
-func (h *WSHandler) HandleRestart(w http.ResponseWriter, r *http.Request) {
-	cookie, _ := r.Cookie("player_id")
-	parts := strings.Split(cookie.Value, ":")
-	playerID := parts[0]
-	roomID := r.URL.Query().Get("roomID")
-
-	room, err := h.Manager.GetRoom(roomID)
+// errorBannerHTML reemplaza el contenido de la pantalla por un mensaje de error.
+// El mensaje pasa por html/template, nunca se concatena directo en el HTML.
+func (h *WSHandler) errorBannerHTML(lang, message string) string {
+	out, err := h.GameH.renderFragment(lang, "error_banner", message)
 	if err != nil {
-		http.Error(w, "Sala no encontrada", http.StatusNotFound)
-		return
+		slog.Error("error renderizando template", "template", "error_banner", "err", err)
+		return `<div id="content" hx-swap-oob="innerHTML"><div class="text-red-400 text-center p-8">` + i18n.T(lang, "error.internal") + `</div></div>`
 	}
-
-	// Validar que sea Host
-	isHost := false
-	if p, ok := room.Players[playerID]; ok && p.IsHost {
-		isHost = true
-	}
-	if !isHost {
-		http.Error(w, "Solo el host puede reiniciar", http.StatusForbidden)
-		return
-	}
-	room.Reset()
-	h.broadcastGameState(roomID)
-	h.BroadcastPlayerList(roomID)
-	w.WriteHeader(http.StatusOK)
+	return out
 }
 
 // simplificar pasar de string a int
 func atoi(s string) int {
 	i, _ := strconv.Atoi(s)
 	return i
-}
-
-func (h *WSHandler) HandleLiar(w http.ResponseWriter, r *http.Request) {
-	// Identificar jugador y sala
-	cookie, _ := r.Cookie("player_id")
-	parts := strings.Split(cookie.Value, ":")
-	playerID := parts[0]
-	roomID := r.URL.Query().Get("roomID")
-
-	room, err := h.Manager.GetRoom(roomID)
-	if err != nil {
-		http.Error(w, "Sala no encontrada", http.StatusNotFound)
-		return
-	}
-
-	_, err = room.CallLiar(playerID)
-	if err != nil {
-		fmt.Printf("Error CallLiar: %v\n", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	h.broadcastGameState(roomID)
-	w.WriteHeader(http.StatusOK)
-}
-
-func (h *WSHandler) HandleUpdateConfig(w http.ResponseWriter, r *http.Request) {
-	// Identificar Host y Sala
-	cookie, _ := r.Cookie("player_id")
-	parts := strings.Split(cookie.Value, ":")
-	playerID := parts[0]
-	roomID := r.URL.Query().Get("roomID")
-
-	room, err := h.Manager.GetRoom(roomID)
-	if err != nil {
-		http.Error(w, "Sala no encontrada", http.StatusNotFound)
-		return
-	}
-
-	// Verificar Permisos
-	isHost := false
-	if p, ok := room.Players[playerID]; ok && p.IsHost {
-		isHost = true
-	}
-	if !isHost {
-		http.Error(w, "Solo el host puede configurar", http.StatusForbidden)
-		return
-	}
-
-	// Procesar Formulario
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "Datos inválidos", http.StatusBadRequest)
-		return
-	}
-
-	// Actualizar Configuración (Protegido por Mutex)
-	room.Mutex.Lock()
-	room.Config.DicesAmount = atoi(r.FormValue("dices_amount"))
-	room.Config.TurnDuration = atoi(r.FormValue("turn_duration"))
-	room.Config.MaxPlayers = atoi(r.FormValue("max_players"))
-	room.Config.MinBetIncrement = atoi(r.FormValue("min_bet_increment"))
-	room.Config.WildAces = (r.FormValue("wild_aces") == "on")
-	
-	// Validaciones de seguridad
-	if room.Config.MaxPlayers < 2 { room.Config.MaxPlayers = 2 }
-	if room.Config.DicesAmount < 1 { room.Config.DicesAmount = 5 }
-	if room.Config.MinBetIncrement < 1 { room.Config.MinBetIncrement = 1 }
-	
-	room.Mutex.Unlock()
-
-	fmt.Printf("✅ SALA ACTUALIZADA: Dados=%d, Tiempo=%d\n", room.Config.DicesAmount, room.Config.TurnDuration)
-
-	h.broadcastGameState(roomID)
-	w.WriteHeader(http.StatusOK)
-}
-
-func (h *WSHandler) HandleNextRound(w http.ResponseWriter, r *http.Request) {
-	cookie, _ := r.Cookie("player_id")
-	parts := strings.Split(cookie.Value, ":")
-	playerID := parts[0]
-	roomID := r.URL.Query().Get("roomID")
-
-	room, err := h.Manager.GetRoom(roomID)
-	if err != nil {
-		http.Error(w, "Sala no encontrada", http.StatusNotFound)
-		return
-	}
-
-	isHost := false
-	if p, ok := room.Players[playerID]; ok && p.IsHost {
-		isHost = true
-	}
-	if !isHost {
-		http.Error(w, "Solo el host puede avanzar ronda", http.StatusForbidden)
-		return
-	}
-
-	room.NextRound()
-	h.broadcastGameState(roomID)
-	w.WriteHeader(http.StatusOK)
 }
\ No newline at end of file
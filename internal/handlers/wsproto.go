@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"dados-mentirosos/internal/game"
+	"dados-mentirosos/internal/i18n"
+	"encoding/json"
+	"log/slog"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+)
+
+// JSONProtocol es el sub-protocolo WebSocket para clientes que no son el navegador.
+// Se negocia con el header Sec-WebSocket-Protocol en el mismo /ws/{roomID}.
+const JSONProtocol = "dados.v1.json"
+
+// ProtocolVersion es la version del esquema de mensajes (campo "v")
+const ProtocolVersion = 1
+
+// serverMessage es todo lo que el servidor manda a un cliente JSON.
+// Tipos: "state" (snapshot de la sala), "event", "chat", "ack" y "error".
+type serverMessage struct {
+	V    int    `json:"v"`
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"` // id del comando que se responde (ack/error)
+	Data any    `json:"data,omitempty"`
+}
+
+// clientMessage es un comando del cliente JSON.
+// Tipos: "bid", "liar", "start", "ready", "chat", "react" y, para el host, "mute" y "clear-chat".
+type clientMessage struct {
+	V    int             `json:"v"`
+	Type string          `json:"type"`
+	ID   string          `json:"id,omitempty"` // opcional, se devuelve en el ack/error
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// requestedProtocol devuelve "json" si el cliente pidio el sub-protocolo JSON
+func requestedProtocol(r *http.Request) string {
+	for _, p := range websocket.Subprotocols(r) {
+		if p == JSONProtocol {
+			return "json"
+		}
+	}
+	return "html"
+}
+
+// isJSONSession indica si la sesion habla el protocolo JSON en vez de fragmentos HTML
+func isJSONSession(s *melody.Session) bool {
+	p, ok := s.Get("protocol")
+	return ok && p.(string) == "json"
+}
+
+// encodeServerMessage arma el JSON de un mensaje del servidor
+func encodeServerMessage(msgType, id string, data any) []byte {
+	msg, err := json.Marshal(serverMessage{V: ProtocolVersion, Type: msgType, ID: id, Data: data})
+	if err != nil {
+		slog.Error("error serializando mensaje WS", "type", msgType, "err", err)
+		return nil
+	}
+	return msg
+}
+
+// writeJSONState manda el snapshot de la sala tal como lo ve el jugador
+func (h *WSHandler) writeJSONState(s *melody.Session, room *game.Room, playerID string) {
+	view, err := newRoomView(room, playerID)
+	if err != nil {
+		h.writeJSONError(s, "", err)
+		return
+	}
+	s.Write(encodeServerMessage("state", "", view))
+}
+
+// writeJSONError responde un error al que mando el comando
+func (h *WSHandler) writeJSONError(s *melody.Session, id string, err error) {
+	s.Write(encodeServerMessage("error", id, translateError(sessionLang(s), "", err).Body))
+}
+
+// handleMessage recibe los comandos de los clientes JSON y las acciones del navegador
+func (h *WSHandler) handleMessage(s *melody.Session, raw []byte) {
+	lang := sessionLang(s)
+	if !h.allowMessage(s) {
+		if isJSONSession(s) {
+			s.Write(encodeServerMessage("error", "", apiError{Code: codeRateLimited, Message: i18n.T(lang, "error.too_many_actions")}))
+		} else {
+			s.Write([]byte(h.actionFeedbackHTML(lang, "", i18n.T(lang, "error.too_many_actions"))))
+		}
+		return
+	}
+
+	if !isJSONSession(s) {
+		h.handleHTMLAction(s, raw)
+		return
+	}
+
+	roomID := s.MustGet("roomID").(string)
+	playerID := s.MustGet("playerID").(string)
+
+	var msg clientMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		s.Write(encodeServerMessage("error", "", apiError{Code: codeInvalidMessage, Message: i18n.T(lang, "error.invalid_message", err.Error())}))
+		return
+	}
+	if msg.V != ProtocolVersion {
+		s.Write(encodeServerMessage("error", msg.ID, apiError{Code: codeUnsupportedVersion, Message: i18n.T(lang, "error.unsupported_version", ProtocolVersion)}))
+		return
+	}
+
+	room, err := h.Manager.GetRoom(roomID)
+	if err != nil {
+		h.writeJSONError(s, msg.ID, err)
+		return
+	}
+
+	switch msg.Type {
+	case "bid":
+		var data struct {
+			Quantity int `json:"quantity"`
+			Face     int `json:"face"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			s.Write(encodeServerMessage("error", msg.ID, apiError{Code: codeInvalidMessage, Message: i18n.T(lang, "error.invalid_message", err.Error())}))
+			return
+		}
+		err = room.PlaceBet(playerID, data.Quantity, data.Face)
+	case "liar":
+		_, err = room.CallLiar(playerID)
+	case "start":
+		err = room.StartGame(playerID)
+	case "ready":
+		var data struct {
+			Ready bool `json:"ready"`
+		}
+		data.Ready = true // sin data = listo
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &data); err != nil {
+				s.Write(encodeServerMessage("error", msg.ID, apiError{Code: codeInvalidMessage, Message: i18n.T(lang, "error.invalid_message", err.Error())}))
+				return
+			}
+		}
+		err = room.SetReady(playerID, data.Ready)
+	case "chat":
+		var data struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			s.Write(encodeServerMessage("error", msg.ID, apiError{Code: codeInvalidMessage, Message: i18n.T(lang, "error.invalid_message", err.Error())}))
+			return
+		}
+		err = h.sendChat(room, playerID, data.Text)
+	case "mute":
+		var data struct {
+			PlayerID string `json:"player_id"`
+			Muted    *bool  `json:"muted"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			s.Write(encodeServerMessage("error", msg.ID, apiError{Code: codeInvalidMessage, Message: i18n.T(lang, "error.invalid_message", err.Error())}))
+			return
+		}
+		muted := data.Muted == nil || *data.Muted // sin muted = silenciar
+		err = room.Mute(playerID, data.PlayerID, muted)
+	case "clear-chat":
+		err = room.ClearChat(playerID)
+	case "react":
+		var data struct {
+			Emoji string `json:"emoji"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			s.Write(encodeServerMessage("error", msg.ID, apiError{Code: codeInvalidMessage, Message: i18n.T(lang, "error.invalid_message", err.Error())}))
+			return
+		}
+		err = h.sendReaction(room, playerID, data.Emoji)
+	default:
+		s.Write(encodeServerMessage("error", msg.ID, apiError{Code: codeUnknownType, Message: i18n.T(lang, "error.unknown_type", msg.Type)}))
+		return
+	}
+
+	logAction(sessionLogger(s), msg.Type, err)
+	if err != nil {
+		h.writeJSONError(s, msg.ID, err)
+		return
+	}
+	// el estado nuevo le llega a todos con la actualizacion que publica la sala
+	s.Write(encodeServerMessage("ack", msg.ID, nil))
+}
+
+// htmlAction es lo que manda la extension ws de htmx con ws-send: los valores del
+// formulario (siempre strings) mas un campo "action" que indica que hacer
+type htmlAction map[string]any
+
+func (a htmlAction) get(key string) string {
+	if v, ok := a[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// handleHTMLAction ejecuta una accion del navegador y responde solo al que la mando
+func (h *WSHandler) handleHTMLAction(s *melody.Session, raw []byte) {
+	roomID := s.MustGet("roomID").(string)
+	playerID := s.MustGet("playerID").(string)
+	lang := sessionLang(s)
+
+	var action htmlAction
+	if err := json.Unmarshal(raw, &action); err != nil {
+		s.Write([]byte(h.actionFeedbackHTML(lang, "", i18n.T(lang, "error.invalid_message", err.Error()))))
+		return
+	}
+	name := action.get("action")
+
+	room, err := h.Manager.GetRoom(roomID)
+	if err != nil {
+		s.Write([]byte(h.actionFeedbackHTML(lang, name, translateError(lang, name, err).Body.Message)))
+		return
+	}
+
+	switch name {
+	case "bet":
+		err = room.PlaceBet(playerID, atoi(action.get("quantity")), atoi(action.get("face")))
+	case "liar":
+		_, err = room.CallLiar(playerID)
+	case "start":
+		err = room.StartGame(playerID)
+	case "config":
+		err = room.UpdateConfig(playerID, game.GameConfig{
+			DicesAmount:     atoi(action.get("dices_amount")),
+			TurnDuration:    atoi(action.get("turn_duration")),
+			MaxPlayers:      atoi(action.get("max_players")),
+			MinBetIncrement: atoi(action.get("min_bet_increment")),
+			WildAces:        action.get("wild_aces") == "on",
+		})
+	case "next-round":
+		err = room.NextRound(playerID)
+	case "restart":
+		err = room.Reset(playerID)
+	case "chat":
+		err = h.sendChat(room, playerID, action.get("text"))
+	case "mute":
+		err = room.Mute(playerID, action.get("player_id"), action.get("muted") == "on")
+	case "clear-chat":
+		err = room.ClearChat(playerID)
+	case "react":
+		err = h.sendReaction(room, playerID, action.get("emoji"))
+	default:
+		err = fmt.Errorf("%w: %q", game.ErrUnknownAction, name)
+	}
+
+	logAction(sessionLogger(s), name, err)
+	if err != nil {
+		s.Write([]byte(h.actionFeedbackHTML(lang, name, translateError(lang, name, err).Body.Message)))
+		return
+	}
+
+	// ack: se limpian los errores anteriores (el estado nuevo llega con la actualizacion de la sala)
+	s.Write([]byte(h.actionFeedbackHTML(lang, name, "")))
+}
+
+// actionFeedbackHTML arma el fragmento de respuesta a una accion (mensaje vacio = ack).
+// Si la accion tiene su propio lugar para errores (ver actionTarget) tambien se actualiza.
+func (h *WSHandler) actionFeedbackHTML(lang, action, message string) string {
+	out, err := h.GameH.renderFragment(lang, "action_feedback", map[string]interface{}{
+		"Action":  action,
+		"Message": message,
+		"Target":  actionTarget(action),
+	})
+	if err != nil {
+		slog.Error("error renderizando template", "template", "action_feedback", "err", err)
+		return ""
+	}
+	return out
+}
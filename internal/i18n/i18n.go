@@ -0,0 +1,101 @@
+// Package i18n tiene los catalogos de mensajes de la interfaz (es, en, pt) y
+// resuelve el idioma de cada pedido.
+//
+// Los catalogos son archivos JSON planos (clave -> mensaje) en locales/, embebidos
+// en el binario. Para agregar un idioma alcanza con sumar su archivo y su entrada
+// en Languages.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Default es el idioma cuando el cliente no pide ninguno soportado, y el catalogo
+// de respaldo cuando a otro le falta una clave
+const Default = "es"
+
+//go:embed locales/*.json
+var files embed.FS
+
+// Language es un idioma soportado, con su nombre para el selector
+type Language struct {
+	Code string
+	Name string
+}
+
+// Languages son los idiomas soportados, en el orden en que se muestran
+var Languages = []Language{
+	{Code: "es", Name: "Español"},
+	{Code: "en", Name: "English"},
+	{Code: "pt", Name: "Português"},
+}
+
+var catalogs = make(map[string]map[string]string)
+
+func init() {
+	for _, lang := range Languages {
+		data, err := files.ReadFile("locales/" + lang.Code + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: falta el catalogo %s: %v", lang.Code, err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: catalogo %s invalido: %v", lang.Code, err))
+		}
+		catalogs[lang.Code] = messages
+	}
+}
+
+// Normalize reduce una etiqueta como "pt-BR" o "EN_us" al idioma soportado ("pt", "en").
+// Devuelve "" si no hay catalogo para ese idioma.
+func Normalize(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	return ""
+}
+
+// Match elige el idioma a partir del header Accept-Language, respetando los q=.
+// Sin ningun idioma soportado devuelve Default.
+func Match(acceptLanguage string) string {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		// ante el mismo q gana el que vino primero
+		if lang := Normalize(tag); lang != "" && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	return best
+}
+
+// T devuelve el mensaje de la clave en el idioma pedido. Si al catalogo le falta la
+// clave se usa el de Default, y si tampoco esta se devuelve la clave misma.
+// Con args, el mensaje es un formato de fmt.Sprintf.
+func T(lang, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg, ok = catalogs[Default][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
@@ -0,0 +1,29 @@
+// Package logging arma el logger estructurado (log/slog) del servidor.
+package logging
+
+import (
+	"io"
+	"log/slog"
+)
+
+// New crea un logger con el nivel ("debug", "info", "warn", "error") y el formato
+// ("text" o "json") indicados. Los valores invalidos ya los rechaza la configuracion.
+func New(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(level)}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+func parseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,263 @@
+// Package metrics implementa lo minimo del formato de texto de Prometheus
+// (contadores, histogramas y gauges calculados al momento del scrape) sin dependencias.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metric es cualquier cosa que sabe escribirse en el formato de texto
+type metric interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMutex sync.Mutex
+	registry      []metric
+)
+
+func register(m metric) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry = append(registry, m)
+}
+
+// Handler sirve todas las metricas registradas
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		registryMutex.Lock()
+		metrics := append([]metric(nil), registry...)
+		registryMutex.Unlock()
+		for _, m := range metrics {
+			m.write(w)
+		}
+	})
+}
+
+// labelSet guarda las combinaciones de valores de etiquetas que aparecieron.
+// Los valores van en el mismo orden en que se declararon las etiquetas.
+type labelSet struct {
+	names  []string
+	mutex  sync.Mutex
+	keys   []string            // orden de aparicion, para una salida estable
+	values map[string][]string // clave -> valores de las etiquetas
+}
+
+func newLabelSet(names []string) *labelSet {
+	return &labelSet{names: names, values: make(map[string][]string)}
+}
+
+// key arma la clave interna de una combinacion de valores (se llama con el mutex tomado)
+func (l *labelSet) key(values []string) string {
+	if len(values) != len(l.names) {
+		panic(fmt.Sprintf("metrics: se esperaban %d etiquetas, llegaron %d", len(l.names), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	if _, ok := l.values[key]; !ok {
+		l.values[key] = append([]string(nil), values...)
+		l.keys = append(l.keys, key)
+	}
+	return key
+}
+
+// Counter es un contador que solo sube, opcionalmente con etiquetas
+type Counter struct {
+	name, help string
+	labels     *labelSet
+	counts     map[string]float64
+}
+
+// NewCounter crea y registra un contador
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{name: name, help: help, labels: newLabelSet(labels), counts: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc suma uno
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add suma v (que no puede ser negativo)
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.labels.mutex.Lock()
+	defer c.labels.mutex.Unlock()
+	c.counts[c.labels.key(labelValues)] += v
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.labels.mutex.Lock()
+	defer c.labels.mutex.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	if len(c.labels.names) == 0 && len(c.counts) == 0 {
+		fmt.Fprintf(w, "%s 0\n", c.name) // sin etiquetas se expone aunque todavia sea 0
+	}
+	for _, key := range c.labels.keys {
+		writeSample(w, c.name, c.labels.names, c.labels.values[key], c.counts[key])
+	}
+}
+
+// DefBuckets son los limites por defecto de los histogramas (en segundos)
+var DefBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// Histogram cuenta observaciones por rango (por ejemplo duraciones)
+type Histogram struct {
+	name, help string
+	buckets    []float64
+	labels     *labelSet
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // por bucket, no acumulado
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram crea y registra un histograma con los limites dados (nil = DefBuckets)
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	h := &Histogram{name: name, help: help, buckets: buckets, labels: newLabelSet(labels), series: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+// Observe registra un valor
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.labels.mutex.Lock()
+	defer h.labels.mutex.Unlock()
+
+	key := h.labels.key(labelValues)
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, limit := range h.buckets {
+		if v <= limit {
+			s.counts[i]++
+			break
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// Since registra el tiempo transcurrido desde start, en segundos
+func (h *Histogram) Since(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.labels.mutex.Lock()
+	defer h.labels.mutex.Unlock()
+
+	writeHeader(w, h.name, h.help, "histogram")
+	names := append(append([]string(nil), h.labels.names...), "le")
+	for _, key := range h.labels.keys {
+		s := h.series[key]
+		values := h.labels.values[key]
+		var cumulative uint64
+		for i, limit := range h.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, h.name+"_bucket", names, append(append([]string(nil), values...), formatFloat(limit)), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", names, append(append([]string(nil), values...), "+Inf"), float64(s.count))
+		writeSample(w, h.name+"_sum", h.labels.names, values, s.sum)
+		writeSample(w, h.name+"_count", h.labels.names, values, float64(s.count))
+	}
+}
+
+// Sample es un valor de un gauge calculado en el momento
+type Sample struct {
+	Labels []string // valores, en el orden de las etiquetas declaradas
+	Value  float64
+}
+
+// GaugeFunc es un gauge cuyo valor se calcula en cada scrape
+type GaugeFunc struct {
+	name, help string
+	labels     []string
+	collect    func() []Sample
+}
+
+// NewGaugeFunc crea y registra un gauge calculado por collect
+func NewGaugeFunc(name, help string, labels []string, collect func() []Sample) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, labels: labels, collect: collect}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	samples := g.collect()
+	sort.SliceStable(samples, func(i, j int) bool {
+		return strings.Join(samples[i].Labels, "\xff") < strings.Join(samples[j].Labels, "\xff")
+	})
+
+	writeHeader(w, g.name, g.help, "gauge")
+	for _, s := range samples {
+		writeSample(w, g.name, g.labels, s.Labels, s.Value)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, kind)
+}
+
+func writeSample(w io.Writer, name string, labelNames, labelValues []string, v float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labelNames) > 0 {
+		b.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(labelValues[i]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+	io.WriteString(w, b.String())
+}
+
+// en el HELP se escapan la barra y el salto de linea; en las etiquetas, tambien las comillas
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// output escribe una metrica en el formato de texto
+func output(m metric) string {
+	var b strings.Builder
+	m.write(&b)
+	return b.String()
+}
+
+func TestCounterExposition(t *testing.T) {
+	c := NewCounter("test_challenges_total", "Desafios por resultado.", "outcome")
+	c.Inc("liar")
+	c.Add(2, "truth")
+	c.Inc("liar")
+	c.Add(-1, "liar") // los contadores no bajan
+
+	want := "# HELP test_challenges_total Desafios por resultado.\n" +
+		"# TYPE test_challenges_total counter\n" +
+		"test_challenges_total{outcome=\"liar\"} 2\n" +
+		"test_challenges_total{outcome=\"truth\"} 2\n"
+	if got := output(c); got != want {
+		t.Errorf("salida:\n%s\nse esperaba:\n%s", got, want)
+	}
+
+	// sin etiquetas se expone en 0 desde el arranque
+	empty := NewCounter("test_rounds_total", "Rondas.")
+	if got := output(empty); !strings.HasSuffix(got, "\ntest_rounds_total 0\n") {
+		t.Errorf("contador vacio:\n%s", got)
+	}
+}
+
+func TestHelpEscaping(t *testing.T) {
+	c := NewCounter("test_help_total", "Primera linea\nsegunda C:\\ linea.")
+	want := `# HELP test_help_total Primera linea\nsegunda C:\\ linea.` + "\n"
+	if got := output(c); !strings.HasPrefix(got, want) {
+		t.Errorf("salida:\n%s\nse esperaba que empiece con:\n%s", got, want)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	g := NewGaugeFunc("test_escape", "Escape.", []string{"value"}, func() []Sample {
+		return []Sample{{Labels: []string{"a\\b \"c\"\nd"}, Value: 1}}
+	})
+	want := `test_escape{value="a\\b \"c\"\nd"} 1` + "\n"
+	if got := output(g); !strings.HasSuffix(got, want) {
+		t.Errorf("salida:\n%s\nse esperaba que termine en:\n%s", got, want)
+	}
+}
+
+func TestHistogramExposition(t *testing.T) {
+	h := NewHistogram("test_render_seconds", "Render.", []float64{0.1, 1}, "template")
+	h.Observe(0.05, "home")
+	h.Observe(0.5, "home")
+	h.Observe(3, "home")
+
+	want := "# HELP test_render_seconds Render.\n" +
+		"# TYPE test_render_seconds histogram\n" +
+		"test_render_seconds_bucket{template=\"home\",le=\"0.1\"} 1\n" +
+		"test_render_seconds_bucket{template=\"home\",le=\"1\"} 2\n" +
+		"test_render_seconds_bucket{template=\"home\",le=\"+Inf\"} 3\n" +
+		"test_render_seconds_sum{template=\"home\"} 3.55\n" +
+		"test_render_seconds_count{template=\"home\"} 3\n"
+	if got := output(h); got != want {
+		t.Errorf("salida:\n%s\nse esperaba:\n%s", got, want)
+	}
+}
+
+func TestGaugeFuncSortsSamples(t *testing.T) {
+	g := NewGaugeFunc("test_rooms", "Salas.", []string{"status"}, func() []Sample {
+		return []Sample{
+			{Labels: []string{"waiting"}, Value: 2},
+			{Labels: []string{"finished"}, Value: 0},
+			{Labels: []string{"playing"}, Value: math.Inf(1)},
+		}
+	})
+	want := "# HELP test_rooms Salas.\n" +
+		"# TYPE test_rooms gauge\n" +
+		"test_rooms{status=\"finished\"} 0\n" +
+		"test_rooms{status=\"playing\"} +Inf\n" +
+		"test_rooms{status=\"waiting\"} 2\n"
+	if got := output(g); got != want {
+		t.Errorf("salida:\n%s\nse esperaba:\n%s", got, want)
+	}
+}
+
+func TestWrongLabelCountPanics(t *testing.T) {
+	c := NewCounter("test_labels_total", "Etiquetas.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("se esperaba un panic con una etiqueta de menos")
+		}
+	}()
+	c.Inc("solo-una")
+}
+
+func TestHandler(t *testing.T) {
+	NewCounter("test_handler_total", "Handler.").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "\ntest_handler_total 1\n") {
+		t.Errorf("el handler no expone el contador:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Bucket es un token bucket: se llena a Rate fichas por segundo hasta Burst
+// y cada pedido gasta una. No es seguro para usar desde varias goroutines.
+type Bucket struct {
+	Rate   float64
+	Burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket crea un bucket lleno
+func NewBucket(rate float64, burst int) *Bucket {
+	return &Bucket{Rate: rate, Burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Allow gasta una ficha si hay, si no devuelve false
+func (b *Bucket) Allow() bool {
+	return b.allowAt(time.Now())
+}
+
+func (b *Bucket) allowAt(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.Rate
+	if b.tokens > b.Burst {
+		b.tokens = b.Burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full indica si el bucket ya se relleno (se puede olvidar sin cambiar nada)
+func (b *Bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.Rate >= b.Burst
+}
+
+// Limiter mantiene un bucket por clave (IP, jugador, ...)
+type Limiter struct {
+	mutex     sync.Mutex
+	rate      float64
+	burst     int
+	buckets   map[string]*Bucket
+	lastSweep time.Time
+}
+
+// New crea un limitador de rate pedidos por segundo con rafagas de hasta burst.
+// Con rate <= 0 no limita nada.
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   make(map[string]*Bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// PerMinute crea un limitador de n pedidos por minuto (rafaga de n)
+func PerMinute(n int) *Limiter {
+	return New(float64(n)/60, n)
+}
+
+// Allow indica si la clave puede hacer un pedido mas
+func (l *Limiter) Allow(key string) bool {
+	if l.rate <= 0 {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &Bucket{Rate: l.rate, Burst: float64(l.burst), tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	return b.allowAt(now)
+}
+
+// sweep borra cada tanto los buckets llenos para que el mapa no crezca sin fin.
+// Se llama con el mutex tomado.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
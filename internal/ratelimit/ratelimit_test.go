@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketBurstAndRefill(t *testing.T) {
+	start := time.Now()
+	b := &Bucket{Rate: 2, Burst: 3, tokens: 3, last: start}
+
+	for i := 0; i < 3; i++ {
+		if !b.allowAt(start) {
+			t.Fatalf("el pedido %d de la rafaga deberia pasar", i+1)
+		}
+	}
+	if b.allowAt(start) {
+		t.Fatal("con el bucket vacio no deberia pasar")
+	}
+
+	// a 2 fichas por segundo, en medio segundo vuelve una
+	if !b.allowAt(start.Add(500 * time.Millisecond)) {
+		t.Error("despues de medio segundo deberia haber una ficha")
+	}
+	if b.allowAt(start.Add(500 * time.Millisecond)) {
+		t.Error("solo se relleno una ficha")
+	}
+
+	// nunca junta mas que la rafaga
+	later := start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !b.allowAt(later) {
+			t.Fatalf("el pedido %d despues de una hora deberia pasar", i+1)
+		}
+	}
+	if b.allowAt(later) {
+		t.Error("el bucket junto mas fichas que la rafaga")
+	}
+}
+
+func TestLimiterPerKey(t *testing.T) {
+	l := New(1, 2)
+	for i := 0; i < 2; i++ {
+		if !l.Allow("10.0.0.1") {
+			t.Fatalf("el pedido %d deberia pasar", i+1)
+		}
+	}
+	if l.Allow("10.0.0.1") {
+		t.Error("la tercera rafaga deberia cortarse")
+	}
+	if !l.Allow("10.0.0.2") {
+		t.Error("otra clave tiene su propio bucket")
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := New(0, 0)
+	for i := 0; i < 1000; i++ {
+		if !l.Allow("x") {
+			t.Fatal("con rate 0 no deberia limitar")
+		}
+	}
+}
+
+func TestLimiterSweepsFullBuckets(t *testing.T) {
+	l := PerMinute(60)
+	l.Allow("lleno")
+	for i := 0; i < 60; i++ {
+		l.Allow("vacio")
+	}
+
+	// a una ficha por segundo, en 59 segundos el que gasto una ya se relleno y el que
+	// gasto las 60 todavia no
+	now := time.Now().Add(59 * time.Second)
+	l.lastSweep = now.Add(-time.Minute)
+	l.sweep(now)
+	if _, ok := l.buckets["lleno"]; ok {
+		t.Error("el bucket que ya se relleno deberia borrarse")
+	}
+	if _, ok := l.buckets["vacio"]; !ok {
+		t.Error("el bucket que todavia no se relleno no se puede olvidar")
+	}
+}
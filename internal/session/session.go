@@ -0,0 +1,107 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("sesion invalida")
+	ErrExpiredToken = errors.New("sesion vencida")
+)
+
+// Session es la identidad de un jugador, la emite y firma el servidor
+type Session struct {
+	PlayerID string    `json:"id"`
+	Name     string    `json:"name"`
+	IssuedAt time.Time `json:"iat"`
+}
+
+// Signer emite y verifica tokens firmados con HMAC-SHA256.
+// El token es base64(payload JSON) + "." + base64(firma), asi el cliente
+// puede leerlo pero no modificarlo sin conocer el secreto del servidor.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time // reloj, se reemplaza en las pruebas
+}
+
+// NewSigner crea un Signer con el secreto dado. Los tokens vencen ttl despues de emitidos.
+func NewSigner(secret []byte, ttl time.Duration) *Signer {
+	return &Signer{secret: secret, ttl: ttl, now: time.Now}
+}
+
+// TTL es cuanto vale un token desde que se emite
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// RandomSecret genera un secreto aleatorio (los tokens dejan de valer al reiniciar)
+func RandomSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("no se pudo generar el secreto de sesion: " + err.Error())
+	}
+	return secret
+}
+
+// Issue emite un token para el jugador
+func (s *Signer) Issue(playerID, name string) string {
+	payload, _ := json.Marshal(Session{PlayerID: playerID, Name: name, IssuedAt: s.now().UTC()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded))
+}
+
+// Verify comprueba la firma y el vencimiento y devuelve la sesion del token
+func (s *Signer) Verify(token string) (Session, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Session{}, ErrInvalidToken
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.sign(encoded)) {
+		return Session{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Session{}, ErrInvalidToken
+	}
+	var sess Session
+	if err := json.Unmarshal(payload, &sess); err != nil || sess.PlayerID == "" {
+		return Session{}, ErrInvalidToken
+	}
+	// un token sin fecha (o con una muy adelantada) tampoco vale
+	age := s.now().Sub(sess.IssuedAt)
+	if age > s.ttl || age < -time.Minute {
+		return Session{}, ErrExpiredToken
+	}
+	return sess, nil
+}
+
+// CSRFToken deriva el token anti-CSRF de una clave (el ID del jugador o, antes de
+// tener sesion, el identificador anonimo del navegador). No hace falta guardarlo.
+func (s *Signer) CSRFToken(key string) string {
+	return base64.RawURLEncoding.EncodeToString(s.sign("csrf:" + key))
+}
+
+// CheckCSRF compara en tiempo constante el token recibido con el esperado para la clave
+func (s *Signer) CheckCSRF(key, token string) bool {
+	if key == "" || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(s.CSRFToken(key)))
+}
+
+func (s *Signer) sign(data string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,78 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	s := NewSigner([]byte("un-secreto-de-prueba"), time.Hour)
+
+	sess, err := s.Verify(s.Issue("ana-id", "Ana"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.PlayerID != "ana-id" || sess.Name != "Ana" {
+		t.Errorf("sesion = %+v", sess)
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	s := NewSigner([]byte("un-secreto-de-prueba"), time.Hour)
+	token := s.Issue("ana-id", "Ana")
+	encoded, sig, _ := strings.Cut(token, ".")
+
+	// otro jugador con la firma de Ana
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"id":"beto-id","name":"Beto","iat":"` +
+		time.Now().UTC().Format(time.RFC3339Nano) + `"}`))
+
+	cases := map[string]string{
+		"payload cambiado": forged + "." + sig,
+		"firma cambiada":   encoded + "." + base64.RawURLEncoding.EncodeToString([]byte("firma")),
+		"sin firma":        encoded,
+		"vacio":            "",
+		"otro secreto":     NewSigner([]byte("otro-secreto-de-prueba"), time.Hour).Issue("ana-id", "Ana"),
+	}
+	for name, token := range cases {
+		if _, err := s.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: Verify = %v, se esperaba ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestVerifyExpires(t *testing.T) {
+	s := NewSigner([]byte("un-secreto-de-prueba"), time.Hour)
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	token := s.Issue("ana-id", "Ana")
+
+	s.now = func() time.Time { return now.Add(59 * time.Minute) }
+	if _, err := s.Verify(token); err != nil {
+		t.Errorf("antes de vencer: %v", err)
+	}
+
+	s.now = func() time.Time { return now.Add(61 * time.Minute) }
+	if _, err := s.Verify(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("vencido: Verify = %v, se esperaba ErrExpiredToken", err)
+	}
+
+	// emitido "en el futuro" (reloj adelantado o token armado a mano)
+	s.now = func() time.Time { return now.Add(-time.Hour) }
+	if _, err := s.Verify(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("del futuro: Verify = %v, se esperaba ErrExpiredToken", err)
+	}
+}
+
+func TestCSRFToken(t *testing.T) {
+	s := NewSigner([]byte("un-secreto-de-prueba"), time.Hour)
+	token := s.CSRFToken("ana-id")
+	if !s.CheckCSRF("ana-id", token) {
+		t.Error("el token CSRF de Ana no valida")
+	}
+	if s.CheckCSRF("beto-id", token) || s.CheckCSRF("ana-id", "") || s.CheckCSRF("", token) {
+		t.Error("el token CSRF valida con otra clave o vacio")
+	}
+}
@@ -0,0 +1,9 @@
+// Package ui contiene los templates HTML, embebidos en el binario.
+package ui
+
+import "embed"
+
+// HTML tiene todo lo que esta bajo ui/html (base, pages y partials)
+//
+//go:embed html
+var HTML embed.FS